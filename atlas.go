@@ -0,0 +1,72 @@
+package gomuni
+
+import (
+	"sort"
+	"time"
+)
+
+//Edition is a Country with the date from which its boundaries are valid
+type Edition struct {
+	ValidFrom time.Time
+	Country   *Country
+}
+
+//Atlas holds several vintages of the Country, to query the boundaries valid at a date.
+//Each Edition is valid from its date until the date of the next one.
+type Atlas struct {
+	editions []Edition
+}
+
+//NewAtlas returns an Atlas with the provided Editions
+func NewAtlas(editions ...Edition) *Atlas {
+	a := &Atlas{}
+	for _, e := range editions {
+		a.Add(e.ValidFrom, e.Country)
+	}
+	return a
+}
+
+//Add adds a Country valid from the provided date, replacing the one with the same date
+func (a *Atlas) Add(validFrom time.Time, country *Country) {
+	i := sort.Search(len(a.editions), func(i int) bool { return !a.editions[i].ValidFrom.Before(validFrom) })
+	if i < len(a.editions) && a.editions[i].ValidFrom.Equal(validFrom) {
+		a.editions[i].Country = country
+		return
+	}
+
+	a.editions = append(a.editions, Edition{})
+	copy(a.editions[i+1:], a.editions[i:])
+	a.editions[i] = Edition{ValidFrom: validFrom, Country: country}
+}
+
+//Editions returns the Editions of the Atlas sorted by date
+func (a *Atlas) Editions() []Edition {
+	return append([]Edition(nil), a.editions...)
+}
+
+//CountryAt returns the Country valid at the provided date, or nil if the date is before the first Edition
+func (a *Atlas) CountryAt(date time.Time) *Country {
+	i := sort.Search(len(a.editions), func(i int) bool { return a.editions[i].ValidFrom.After(date) })
+	if i == 0 {
+		return nil
+	}
+	return a.editions[i-1].Country
+}
+
+//FindTownByPointAt returns the Town containing the Point with the boundaries valid at the provided date
+func (a *Atlas) FindTownByPointAt(point Point, date time.Time) *Town {
+	country := a.CountryAt(date)
+	if country == nil {
+		return nil
+	}
+	return country.FindTownByPoint(point)
+}
+
+//GetTownByIDAt returns the Town with the provided ISTAT code in the Country valid at the provided date
+func (a *Atlas) GetTownByIDAt(ID string, date time.Time) *Town {
+	country := a.CountryAt(date)
+	if country == nil {
+		return nil
+	}
+	return country.GetTownByID(ID)
+}
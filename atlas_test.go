@@ -0,0 +1,49 @@
+package gomuni
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Atlas(t *testing.T) {
+	old, current := newTestCountry(), newTestCountry()
+
+	// in the old vintage Moncalieri was part of Torino
+	torino := old.Regions[0].Cities[0]
+	delete(torino.townsMap, "001156")
+	delete(old.townsMap, "001156")
+	torino.Towns = torino.Towns[:1]
+
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	atlas := NewAtlas(Edition{date("2018-01-01"), current}, Edition{date("2016-01-01"), old})
+
+	tests := []struct {
+		date    string
+		country *Country
+		town    bool
+	}{
+		{"2015-12-31", nil, false},
+		{"2016-01-01", old, false},
+		{"2017-06-30", old, false},
+		{"2018-01-01", current, true},
+		{"2020-01-01", current, true},
+	}
+	for _, tt := range tests {
+		if got := atlas.CountryAt(date(tt.date)); got != tt.country {
+			t.Errorf("CountryAt(%s) returned the wrong edition", tt.date)
+		}
+		if got := atlas.GetTownByIDAt("001156", date(tt.date)); (got != nil) != tt.town {
+			t.Errorf("GetTownByIDAt(001156, %s) = %v, want found %v", tt.date, got, tt.town)
+		}
+	}
+
+	if town := atlas.FindTownByPointAt(Point{Lat: 45, Lng: 7.5}, date("2019-03-01")); town == nil || town.ID != "001272" {
+		t.Errorf("FindTownByPointAt() = %v, want Torino", town)
+	}
+	if town := atlas.FindTownByPointAt(Point{Lat: 45, Lng: 7.5}, date("2000-01-01")); town != nil {
+		t.Errorf("FindTownByPointAt() before the first edition = %v, want nil", town)
+	}
+}
@@ -1,24 +1,24 @@
 package gomuni
 
 import (
-	"github.com/dhconnelly/rtreego"
 	shp "github.com/jonas-p/go-shp"
-	geo "github.com/kellydunn/golang-geo"
 )
 
 //City represent an italian City (provincia)
 type City struct {
-	ID        string  `json:"id,omitempty"`
-	RegionID  string  `json:"region_id,omitempty"`
-	Name      string  `json:"name,omitempty"`
-	Shortname string  `json:"shortname,omitempty"`
-	Maincity  bool    `json:"maincity,omitempty"`
-	Towns     []*Town `json:"towns,omitempty"`
+	ID         string            `json:"id,omitempty"`
+	RegionID   string            `json:"region_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Names      Names             `json:"names,omitempty"`
+	Shortname  string            `json:"shortname,omitempty"`
+	Maincity   bool              `json:"maincity,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Towns      []*Town           `json:"towns,omitempty"`
 
-	BBox      shp.Box `json:"bbox,omitempty"`
-	polygon   *geo.Polygon
-	townsTree *rtreego.Rtree
-	townsMap  map[string]*Town
+	BBox       shp.Box `json:"bbox,omitempty"`
+	polygon    MultiPolygon
+	townsIndex spatialIndex
+	townsMap   map[string]*Town
 }
 
 //TownGetter can be used to retrive a town from its ID or from a geolocation point
@@ -27,11 +27,9 @@ type TownGetter interface {
 	GetTownByPoint(lat, lng float32) []*Town
 }
 
-//Bounds is used to implement the rtreego Spatial interface
-func (c *City) Bounds() *rtreego.Rect {
-	p1 := rtreego.Point{c.BBox.MinX, c.BBox.MinY}
-	r1, _ := rtreego.NewRect(p1, []float64{c.BBox.MaxX - c.BBox.MinX, c.BBox.MaxY - c.BBox.MinY})
-	return r1
+//Contains check if the current City contains the passed in Point.
+func (c *City) Contains(point Point) bool {
+	return c.polygon.Contains(point)
 }
 
 //GetTownByID returns the Town with the provided ID
@@ -41,20 +39,26 @@ func (c *City) GetTownByID(ID string) *Town {
 
 //GetTownsByPoint returns the Towns having their bounding box over the provided geolocation point
 func (c *City) GetTownsByPoint(point Point) []*Town {
-	location := rtreego.Point{point.Lat, point.Lng}
-	results := c.townsTree.SearchIntersect(location.ToRect(0.01))
-
-	cities := make([]*Town, 0)
-	for _, s := range results {
-		r := s.(*Town)
-		cities = append(cities, r)
+	towns := make([]*Town, 0)
+	for _, i := range c.townsIndex.search(pointBox(point, 0.01)) {
+		towns = append(towns, c.Towns[i])
 	}
 
-	return cities
+	return towns
 }
 
 func (c *City) addTown(town *Town) {
 	c.Towns = append(c.Towns, town)
 	c.townsMap[town.ID] = town
-	c.townsTree.Insert(town)
+}
+
+//Geometry returns the boundary of the City, with its holes and islands
+func (c *City) Geometry() Geometry {
+	return c.polygon.Geometry()
+}
+
+//SimplifiedGeometry returns the boundary of the City simplified with the tolerance in metres.
+//Its borders can diverge from the ones of its neighbors, a Topology simplifies them once for all the units.
+func (c *City) SimplifiedGeometry(tolerance float64, method SimplifyMethod) Geometry {
+	return c.polygon.Simplify(tolerance, method).Geometry()
 }
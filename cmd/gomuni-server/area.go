@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+	shp "github.com/jonas-p/go-shp"
+)
+
+//maxAreaBody is the maximum size of a posted GeoJSON polygon
+const maxAreaBody = 10 << 20
+
+//bboxParam reads the bbox parameter, as west,south,east,north like in GeoJSON
+func bboxParam(r *http.Request) (shp.Box, error) {
+	bbox := r.URL.Query().Get("bbox")
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return shp.Box{}, &parameterError{"bbox", bbox, "invalid bbox, expected west,south,east,north"}
+	}
+
+	values := make([]float64, 4)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return shp.Box{}, &parameterError{"bbox", bbox, "invalid bbox, expected west,south,east,north"}
+		}
+		values[i] = v
+	}
+	if values[0] > values[2] || values[1] > values[3] {
+		return shp.Box{}, &parameterError{"bbox", bbox, "invalid bbox, the west and south must be less than the east and north"}
+	}
+
+	// the bounding boxes of the units have the latitudes on the X axis
+	return shp.Box{MinX: values[1], MinY: values[0], MaxX: values[3], MaxY: values[2]}, nil
+}
+
+//bboxHandler returns the units of the level intersecting the bbox parameter
+func (s *service) bboxHandler(level string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bbox, err := bboxParam(r)
+		if err != nil {
+			writeParameterError(w, err)
+			return
+		}
+		s.writeArea(w, r, level, gomuni.BoxPolygon(bbox), true)
+	}
+}
+
+//intersectingHandler returns the units of the level intersecting the posted GeoJSON polygon
+func (s *service) intersectingHandler(level string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// a byte more than the limit is read to find the larger bodies
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxAreaBody+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidBody, err.Error(), nil)
+			return
+		}
+		if len(body) > maxAreaBody {
+			writeError(w, http.StatusRequestEntityTooLarge, codeInvalidBody, "the polygon is larger than "+strconv.Itoa(maxAreaBody)+" bytes", nil)
+			return
+		}
+		polygon, err := gomuni.ParseGeoJSON(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidBody, err.Error(), nil)
+			return
+		}
+		s.writeArea(w, r, level, polygon, false)
+	}
+}
+
+//writeArea writes the units of the level intersecting the polygon, with their parents
+func (s *service) writeArea(w http.ResponseWriter, r *http.Request, level string, polygon gomuni.MultiPolygon, isBox bool) {
+	country := s.country()
+
+	results := make([]response, 0)
+	switch level {
+	case "region":
+		var regions []*gomuni.Region
+		if isBox {
+			regions = country.RegionsInBBox(polygon.BBox())
+		} else {
+			regions = country.RegionsIntersecting(polygon)
+		}
+		for _, region := range regions {
+			results = append(results, response{Region: region})
+		}
+	case "city":
+		var cities []*gomuni.City
+		if isBox {
+			cities = country.CitiesInBBox(polygon.BBox())
+		} else {
+			cities = country.CitiesIntersecting(polygon)
+		}
+		for _, city := range cities {
+			results = append(results, response{Region: country.GetRegionByID(city.RegionID), City: city})
+		}
+	default:
+		var towns []*gomuni.Town
+		if isBox {
+			towns = country.TownsInBBox(polygon.BBox())
+		} else {
+			towns = country.TownsIntersecting(polygon)
+		}
+		for _, town := range towns {
+			results = append(results, response{Region: country.GetRegionByID(town.RegionID), City: country.GetCityByID(town.CityID), Town: town})
+		}
+	}
+
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		fields = responseFields
+	}
+	langs := languages(r)
+
+	reduced := make([]map[string]interface{}, len(results))
+	for i, res := range results {
+		reduced[i] = res.localize(langs).reduce(fields)
+	}
+	writeResults(w, r, results, reduced)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+)
+
+//batchItem is a point to resolve in a batch, with the ID of the client echoed in its result
+type batchItem struct {
+	ID  json.RawMessage `json:"id,omitempty"`
+	Lat *float64        `json:"lat"`
+	Lng *float64        `json:"lng"`
+}
+
+//batchJob is an item of the batch waiting for a worker. The result is sent on its own channel,
+//so that the results can be written in the order of the items while they are resolved concurrently.
+type batchJob struct {
+	raw    json.RawMessage
+	result chan []byte
+}
+
+//batchOptions are the options of the request applied to every item of the batch
+type batchOptions struct {
+	fields  string
+	langs   []string
+	geoJSON bool
+}
+
+//batchQueue is the number of items resolved in advance of the one being written
+const batchQueue = 1024
+
+//batchHandler resolves the points of a JSON array or of NDJSON (one point per line), like
+//{"id": "fix-1", "lat": 45.07, "lng": 7.68}. The results are streamed in the same order and format,
+//an item that cannot be resolved has an error without failing the others.
+func (s *service) batchHandler(w http.ResponseWriter, r *http.Request) {
+	body := bufio.NewReader(r.Body)
+	isArray := firstByte(body) == '['
+
+	country := s.country()
+	opts := batchOptions{fields: r.URL.Query().Get("fields"), langs: languages(r), geoJSON: wantsGeoJSON(r)}
+	if opts.fields == "" {
+		opts.fields = responseFields
+	}
+
+	jobs := make(chan batchJob)
+	pending := make(chan chan []byte, batchQueue)
+
+	// the workers resolve the items on all the cores
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		go func() {
+			for job := range jobs {
+				job.result <- resolveBatchItem(country, job.raw, opts)
+			}
+		}()
+	}
+
+	// the reader queues the items in order, stopping at a malformed JSON array
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+
+		err := decodeBatch(body, isArray, func(raw json.RawMessage) bool {
+			job := batchJob{raw: raw, result: make(chan []byte, 1)}
+			select {
+			case pending <- job.result:
+			case <-done:
+				return false
+			}
+			jobs <- job
+			return true
+		})
+		if err != nil {
+			result := make(chan []byte, 1)
+			result <- batchError(nil, codeInvalidBody, err.Error(), opts)
+			select {
+			case pending <- result:
+			case <-done:
+			}
+		}
+	}()
+
+	switch {
+	case isArray && opts.geoJSON:
+		w.Header().Set("Content-Type", geoJSONType)
+	case isArray:
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	flusher, _ := w.(http.Flusher)
+
+	out := bufio.NewWriter(w)
+	if isArray && opts.geoJSON {
+		out.WriteString(`{"type":"FeatureCollection","features":[`)
+	} else if isArray {
+		out.WriteByte('[')
+	}
+	first := true
+	for result := range pending {
+		b := <-result
+		if isArray && !first {
+			out.WriteByte(',')
+		}
+		first = false
+		out.Write(b)
+		if !isArray {
+			out.WriteByte('\n')
+		}
+
+		// flush when there is nothing ready to write, so that the client gets the results as soon as possible
+		if len(pending) == 0 {
+			out.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if isArray && opts.geoJSON {
+		out.WriteString("]}")
+	} else if isArray {
+		out.WriteByte(']')
+	}
+	out.Flush()
+}
+
+//firstByte returns the first byte of the body that is not a space, without consuming it
+func firstByte(body *bufio.Reader) byte {
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			return 0
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			body.UnreadByte()
+			return b
+		}
+	}
+}
+
+//decodeBatch calls fn with each item of a JSON array or with each line of NDJSON, until fn returns false.
+//A malformed line is passed to fn like the others, to get its own error, while a malformed array stops the batch.
+func decodeBatch(body *bufio.Reader, isArray bool, fn func(json.RawMessage) bool) error {
+	if !isArray {
+		for {
+			line, err := body.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 && !fn(line) {
+				return nil
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	dec := json.NewDecoder(body)
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if !fn(raw) {
+			return nil
+		}
+	}
+
+	_, err := dec.Token()
+	return err
+}
+
+//resolveBatchItem returns the JSON result of an item of the batch, or its GeoJSON Feature
+func resolveBatchItem(country *gomuni.Country, raw json.RawMessage, opts batchOptions) []byte {
+	var item batchItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return batchError(nil, codeInvalidBody, "invalid item, expected an object with id, lat and lng", opts)
+	}
+	if item.Lat == nil || item.Lng == nil {
+		return batchError(item.ID, codeInvalidParameter, "missing lat or lng", opts)
+	}
+	if *item.Lat < -90 || *item.Lat > 90 || *item.Lng < -180 || *item.Lng > 180 {
+		return batchError(item.ID, codeInvalidParameter, "invalid lat or lng, expected a lat between -90 and 90 and a lng between -180 and 180", opts)
+	}
+
+	location := country.Resolve(gomuni.Point{Lat: *item.Lat, Lng: *item.Lng})
+	res := response{Region: location.Region, City: location.City, Town: location.Town}
+	if res.Town == nil {
+		return batchError(item.ID, codeOutsideCountry, "no town found", opts)
+	}
+
+	result := res.localize(opts.langs).reduce(opts.fields)
+	if len(item.ID) > 0 {
+		result["id"] = item.ID
+	}
+	if opts.geoJSON {
+		b, _ := json.Marshal(res.feature(result))
+		return b
+	}
+	b, _ := json.Marshal(result)
+	return b
+}
+
+//batchError returns the JSON result of an item that cannot be resolved, with the same error model of the responses.
+//With GeoJSON it is a Feature without geometry.
+func batchError(id json.RawMessage, code, message string, opts batchOptions) []byte {
+	result := map[string]interface{}{"error": apiError{Code: code, Message: message}}
+	if len(bytes.TrimSpace(id)) > 0 {
+		result["id"] = id
+	}
+	if opts.geoJSON {
+		b, _ := json.Marshal(response{}.feature(result))
+		return b
+	}
+	b, _ := json.Marshal(result)
+	return b
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/enrichman/gomuni"
+	"github.com/joho/godotenv"
+)
+
+//dateLayout is the format of the dates of the configuration and of the at parameter
+const dateLayout = "2006-01-02"
+
+//source is where the dataset is loaded from: a snapshot, a zip archive,
+//the shapefiles folders or the dataset embedded in the binary
+type source struct {
+	snapshot string
+	zip      string
+	opts     gomuni.Options
+
+	//validFrom is the date from which the dataset is valid, vintages are the older datasets
+	validFrom time.Time
+	vintages  []vintage
+
+	//successions is the ISTAT CSV of the administrative changes
+	successions string
+	//names is a CSV of localized names, with the level, id, lang and name columns
+	names string
+}
+
+//vintage is the zip archive of an older dataset, valid from its date
+type vintage struct {
+	validFrom time.Time
+	zip       string
+}
+
+//newSource reads the source from the snapshot flag or from the environment
+func newSource() (source, error) {
+	if err := godotenv.Load(); err == nil {
+		log.Println(".env file loaded")
+	}
+
+	src := source{snapshot: *snapshotFile}
+	if src.snapshot == "" {
+		src.zip = os.Getenv("DATASET_ZIP")
+		src.opts = gomuni.Options{
+			RegionFolder: os.Getenv("REGION_FOLDER"),
+			CityFolder:   os.Getenv("CITY_FOLDER"),
+			TownFolder:   os.Getenv("TOWN_FOLDER"),
+			Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		}
+	}
+
+	src.successions = os.Getenv("SUCCESSIONS_CSV")
+	src.names = os.Getenv("NAMES_CSV")
+
+	var err error
+	if src.vintages, err = parseVintages(os.Getenv("DATASET_VINTAGES")); err != nil {
+		return source{}, err
+	}
+	if validFrom := os.Getenv("DATASET_VALID_FROM"); validFrom != "" {
+		if src.validFrom, err = time.Parse(dateLayout, validFrom); err != nil {
+			return source{}, fmt.Errorf("invalid DATASET_VALID_FROM: %v", err)
+		}
+	} else if len(src.vintages) > 0 {
+		return source{}, fmt.Errorf("DATASET_VALID_FROM is required with DATASET_VINTAGES")
+	}
+	return src, nil
+}
+
+//parseVintages parses a comma separated list of date=zip, like 2016-01-01=shp-files/Limiti_2016_WGS84.zip
+func parseVintages(value string) ([]vintage, error) {
+	vintages := make([]vintage, 0)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid DATASET_VINTAGES entry %q, expected date=zip", entry)
+		}
+		validFrom, err := time.Parse(dateLayout, parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATASET_VINTAGES entry %q: %v", entry, err)
+		}
+		vintages = append(vintages, vintage{validFrom: validFrom, zip: parts[1]})
+	}
+	return vintages, nil
+}
+
+func (s source) embedded() bool {
+	return s.snapshot == "" && s.zip == "" && s.opts.RegionFolder == ""
+}
+
+//load loads the Country from the source
+func (s source) load() (*gomuni.Country, error) {
+	switch {
+	case s.snapshot != "":
+		return loadSnapshot(s.snapshot)
+	case s.zip != "":
+		// the folders are optional inside a zip archive, the layers are found by name
+		log.Println("Loading zip:", s.zip)
+		return gomuni.LoadZip(s.zip, s.opts)
+	case s.embedded():
+		log.Println("No dataset configured, loading the embedded one")
+		return gomuni.Default()
+	}
+
+	log.Println("Loading folders:", s.opts.RegionFolder, s.opts.CityFolder, s.opts.TownFolder)
+	return gomuni.LoadWithOptions(s.opts)
+}
+
+//loadAtlas loads the vintages of the source in an Atlas, with the current Country as the latest edition
+func (s source) loadAtlas(current *gomuni.Country) (*gomuni.Atlas, error) {
+	atlas := gomuni.NewAtlas(gomuni.Edition{ValidFrom: s.validFrom, Country: current})
+	for _, v := range s.vintages {
+		if !v.validFrom.Before(s.validFrom) {
+			return nil, fmt.Errorf("vintage %s is not older than the dataset", v.zip)
+		}
+
+		log.Println("Loading vintage:", v.zip)
+		country, err := gomuni.LoadZip(v.zip, gomuni.Options{Logger: s.opts.Logger})
+		if err != nil {
+			return nil, fmt.Errorf("vintage %s: %v", v.zip, err)
+		}
+		if err := validate(country); err != nil {
+			return nil, fmt.Errorf("vintage %s: %v", v.zip, err)
+		}
+		atlas.Add(v.validFrom, country)
+	}
+	return atlas, nil
+}
+
+//loadNames adds the localized names of the source to the Country, if configured
+func (s source) loadNames(country *gomuni.Country) error {
+	if s.names == "" {
+		return nil
+	}
+
+	log.Println("Loading names:", s.names)
+	f, err := os.Open(s.names)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return country.LoadNames(f)
+}
+
+//loadSuccessions loads the successions of the town codes, empty if not configured
+func (s source) loadSuccessions() (*gomuni.Successions, error) {
+	if s.successions == "" {
+		return gomuni.NewSuccessions(), nil
+	}
+
+	log.Println("Loading successions:", s.successions)
+	f, err := os.Open(s.successions)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return gomuni.LoadSuccessions(f)
+}
+
+//paths returns the files and folders of the source
+func (s source) paths() []string {
+	paths := make([]string, 0)
+	switch {
+	case s.snapshot != "":
+		paths = append(paths, s.snapshot)
+	case s.zip != "":
+		paths = append(paths, s.zip)
+	case s.embedded():
+	default:
+		paths = append(paths, s.opts.RegionFolder, s.opts.CityFolder, s.opts.TownFolder)
+	}
+
+	for _, v := range s.vintages {
+		paths = append(paths, v.zip)
+	}
+	if s.successions != "" {
+		paths = append(paths, s.successions)
+	}
+	if s.names != "" {
+		paths = append(paths, s.names)
+	}
+	return paths
+}
+
+//version returns a fingerprint of the name, size and modification time of every file of the source.
+//It changes when the dataset is updated and it is used as the dataset version.
+func (s source) version() (string, error) {
+	if len(s.paths()) == 0 {
+		return "embedded", nil
+	}
+
+	h := sha1.New()
+	for _, p := range s.paths() {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+func loadSnapshot(filename string) (*gomuni.Country, error) {
+	log.Println("Loading snapshot:", filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return gomuni.LoadSnapshot(bufio.NewReader(f))
+}
+
+func writeSnapshot(country *gomuni.Country, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err := country.WriteSnapshot(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
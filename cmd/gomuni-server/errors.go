@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+//The codes of the errors, stable for the clients while the messages can change
+const (
+	codeInvalidParameter = "invalid_parameter"
+	codeInvalidBody      = "invalid_body"
+	codeNotFound         = "not_found"
+	codeOutsideCountry   = "outside_country"
+	codeDateNotCovered   = "date_not_covered"
+	codeUnauthorized     = "unauthorized"
+	codeConflict         = "conflict"
+	codeInternal         = "internal_error"
+)
+
+//apiError is the JSON body of every error response
+type apiError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+//parameterError is a query parameter missing or with a malformed value
+type parameterError struct {
+	Name    string
+	Value   string
+	Message string
+}
+
+func (e *parameterError) Error() string {
+	return e.Message
+}
+
+//writeError writes the error with the status code
+func writeError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	b, _ := json.Marshal(apiError{Code: code, Message: message, Details: details})
+	w.Write(b)
+}
+
+//writeParameterError writes a 400 Bad Request, with the parameter and its value in the details if known
+func writeParameterError(w http.ResponseWriter, err error) {
+	var details interface{}
+	var paramErr *parameterError
+	if errors.As(err, &paramErr) {
+		details = map[string]string{"parameter": paramErr.Name, "value": paramErr.Value}
+	}
+	writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error(), details)
+}
+
+//writeNotFound writes a 404 Not Found of the unit with the provided ID, the details name the route variable
+func writeNotFound(w http.ResponseWriter, name, id string) {
+	writeError(w, http.StatusNotFound, codeNotFound, name+" "+id+" not found", map[string]string{name: id})
+}
+
+//notFoundHandler answers the unknown routes
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, codeNotFound, "no route for "+r.Method+" "+r.URL.Path, nil)
+}
+
+//recoverer turns a panic of a handler into a 500 Internal Server Error, logging its stack,
+//and sets the JSON Content-Type of the responses, the handlers writing other formats override it
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("Panic serving %s %s: %v\n%s", r.Method, r.URL, err, debug.Stack())
+			writeError(w, http.StatusInternalServerError, codeInternal, "internal server error", nil)
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/enrichman/gofield"
+	"github.com/enrichman/gomuni"
+)
+
+//geoJSONType is the media type of GeoJSON
+const geoJSONType = "application/geo+json"
+
+//wantsGeoJSON checks if the request asks for GeoJSON with format=geojson or with the Accept header.
+//The format parameter wins over the header, format=json returns JSON to any client.
+func wantsGeoJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "geojson"
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(accepted, ";")[0]) == geoJSONType {
+			return true
+		}
+	}
+	return false
+}
+
+//featurer is a unit that can be exported as a GeoJSON Feature
+type featurer interface {
+	Feature() gomuni.Feature
+}
+
+//feature returns the Feature of the deepest unit of the response, with the reduced response as properties
+func (res response) feature(properties map[string]interface{}) gomuni.Feature {
+	var f gomuni.Feature
+	switch {
+	case res.Town != nil:
+		f = res.Town.Feature()
+	case res.City != nil:
+		f = res.City.Feature()
+	case res.Region != nil:
+		f = res.Region.Feature()
+	default:
+		f = gomuni.Feature{Type: "Feature"}
+	}
+	f.Properties = properties
+	return f
+}
+
+//unitFeature returns the Feature of the unit, with the properties reduced to the fields if any
+func unitFeature(unit featurer, fields string) gomuni.Feature {
+	f := unit.Feature()
+	if fields != "" {
+		f.Properties, _ = reduce(unit, fields).(map[string]interface{})
+	}
+	return f
+}
+
+//unitsCollection returns the FeatureCollection of the units, or of the regions of the Country.
+//The fields of the Country select the ones of its regions, like regions{id,name}.
+func unitsCollection(obj interface{}, fields string) gomuni.FeatureCollection {
+	features := make([]gomuni.Feature, 0)
+	switch units := obj.(type) {
+	case *gomuni.Country:
+		regionFields := ""
+		for _, field := range gofield.Split(fields, ",") {
+			if name, inner := splitField(field); name == "regions" {
+				regionFields = inner
+			}
+		}
+		return unitsCollection(units.Regions, regionFields)
+	case []*gomuni.Region:
+		for _, u := range units {
+			features = append(features, unitFeature(u, fields))
+		}
+	case []*gomuni.City:
+		for _, u := range units {
+			features = append(features, unitFeature(u, fields))
+		}
+	case []*gomuni.Town:
+		for _, u := range units {
+			features = append(features, unitFeature(u, fields))
+		}
+	}
+	return gomuni.NewFeatureCollection(features)
+}
+
+//writeUnits writes the units reduced to the fields, as a Feature or a FeatureCollection if GeoJSON is requested
+func writeUnits(w http.ResponseWriter, r *http.Request, obj interface{}, fields string) {
+	if !wantsGeoJSON(r) {
+		b, _ := json.Marshal(reduce(obj, fields))
+		w.Write(b)
+		return
+	}
+	if unit, ok := obj.(featurer); ok {
+		writeGeoJSON(w, unitFeature(unit, fields))
+		return
+	}
+	writeGeoJSON(w, unitsCollection(obj, fields))
+}
+
+//writeResults writes the reduced results, or a FeatureCollection of the deepest unit of each response if GeoJSON is requested
+func writeResults(w http.ResponseWriter, r *http.Request, responses []response, results []map[string]interface{}) {
+	if !wantsGeoJSON(r) {
+		b, _ := json.Marshal(results)
+		w.Write(b)
+		return
+	}
+	features := make([]gomuni.Feature, len(responses))
+	for i, res := range responses {
+		features[i] = res.feature(results[i])
+	}
+	writeGeoJSON(w, gomuni.NewFeatureCollection(features))
+}
+
+//writeGeoJSON writes a GeoJSON object with its media type
+func writeGeoJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", geoJSONType)
+	b, _ := json.Marshal(v)
+	w.Write(b)
+}
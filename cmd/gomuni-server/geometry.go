@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/enrichman/gofield"
+	"github.com/enrichman/gomuni"
+)
+
+//geometryField selects the boundary of a unit, that is not a field of its struct but the result of its Geometry method
+const geometryField = "geometry"
+
+//geometer is a unit with a boundary
+type geometer interface {
+	Geometry() gomuni.Geometry
+}
+
+//simplifier is a unit with a boundary that can be simplified on its own
+type simplifier interface {
+	geometer
+	SimplifiedGeometry(tolerance float64, method gomuni.SimplifyMethod) gomuni.Geometry
+}
+
+//reduce is gofield.Reduce also selecting the geometry of the units, at any depth of the fields,
+//like regions{id,geometry} or town{name,geometry}
+func reduce(obj interface{}, fields string) interface{} {
+	if !strings.Contains(fields, geometryField) {
+		return gofield.Reduce(obj, fields)
+	}
+
+	value := reflect.ValueOf(obj)
+	if value.Kind() == reflect.Slice {
+		reduced := make([]interface{}, value.Len())
+		for i := range reduced {
+			reduced[i] = reduce(value.Index(i).Interface(), fields)
+		}
+		return reduced
+	}
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return obj
+	}
+
+	result := make(map[string]interface{})
+	plain := make([]string, 0)
+	for _, field := range gofield.Split(fields, ",") {
+		name, inner := splitField(field)
+		switch {
+		case name == geometryField:
+			if unit, ok := obj.(geometer); ok {
+				result[name] = gofield.Reduce(unit.Geometry(), inner)
+			}
+		case strings.Contains(inner, geometryField):
+			// the children are reduced here, gofield would drop their geometry
+			children, _ := gofield.Reduce(obj, name).(map[string]interface{})
+			if child, ok := children[name]; ok {
+				result[name] = reduce(child, inner)
+			}
+		default:
+			plain = append(plain, field)
+		}
+	}
+
+	if len(plain) > 0 {
+		reduced, _ := gofield.Reduce(obj, strings.Join(plain, ",")).(map[string]interface{})
+		for k, v := range reduced {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+//splitField splits a field in its name and the fields selected inside it, like town and id,name of town{id,name}
+func splitField(field string) (string, string) {
+	if i := strings.Index(field, "{"); i > -1 && strings.HasSuffix(field, "}") {
+		return field[:i], field[i+1 : len(field)-1]
+	}
+	return field, ""
+}
+
+//simplifyLevels are the tolerances in metres of the named levels of the simplify parameter
+var simplifyLevels = map[string]float64{
+	"fine":   10,
+	"medium": 100,
+	"coarse": 1000,
+}
+
+//simplifyParams reads the tolerance of the simplify parameter, in metres or as a named level,
+//and the algorithm of the simplify_method parameter. The tolerance is 0 if missing.
+//The named levels are shared: a few tolerances cached in the topologies, where the borders of the neighbor units match.
+func simplifyParams(vals url.Values) (tolerance float64, method gomuni.SimplifyMethod, shared bool, err error) {
+	switch value := vals.Get("simplify_method"); value {
+	case "", "douglas-peucker":
+		method = gomuni.DouglasPeucker
+	case "visvalingam":
+		method = gomuni.Visvalingam
+	default:
+		return 0, method, false, &parameterError{"simplify_method", value, "invalid simplify_method, expected douglas-peucker or visvalingam"}
+	}
+
+	if tolerance, ok := simplifyLevels[vals.Get("simplify")]; ok {
+		return tolerance, method, true, nil
+	}
+	tolerance, err = floatParam(vals, "simplify")
+	if err != nil {
+		err = &parameterError{"simplify", vals.Get("simplify"), "invalid simplify, expected a tolerance in metres or fine, medium or coarse"}
+	}
+	return tolerance, method, false, err
+}
+
+func (s *service) regionGeometryHandler(w http.ResponseWriter, r *http.Request) {
+	if region, ok := s.routeRegion(w, r); ok {
+		s.writeGeometry(w, r, region, gomuni.TopologyRegions, region.ID)
+	}
+}
+
+func (s *service) regionCityGeometryHandler(w http.ResponseWriter, r *http.Request) {
+	if city, ok := s.routeCity(w, r); ok {
+		s.writeGeometry(w, r, city, gomuni.TopologyCities, city.ID)
+	}
+}
+
+func (s *service) regionCityTownGeometryHandler(w http.ResponseWriter, r *http.Request) {
+	if town, ok := s.routeTown(w, r); ok {
+		s.writeGeometry(w, r, town, gomuni.TopologyTowns, town.ID)
+	}
+}
+
+//writeGeometry writes the boundary of the unit as a GeoJSON MultiPolygon, with the GeoJSON media type if requested.
+//A named simplify level takes the boundary from the simplified Topology, consistent with the neighbor units,
+//while a tolerance in metres simplifies the unit alone.
+func (s *service) writeGeometry(w http.ResponseWriter, r *http.Request, unit simplifier, layer, id string) {
+	tolerance, method, shared, err := simplifyParams(r.URL.Query())
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+
+	var geometry gomuni.Geometry
+	switch {
+	case shared:
+		topology := s.topologies.get(s.dataset(), gomuni.TopologyOptions{Tolerance: tolerance, Method: method})
+		geometry = unit.Geometry()
+		// the unit can be missing from a dataset reloaded in the meantime
+		if m := topology.MultiPolygon(layer, id); m != nil {
+			geometry = m.Geometry()
+		}
+	case tolerance > 0:
+		geometry = unit.SimplifiedGeometry(tolerance, method)
+	default:
+		geometry = unit.Geometry()
+	}
+
+	if wantsGeoJSON(r) {
+		writeGeoJSON(w, geometry)
+		return
+	}
+	b, _ := json.Marshal(geometry)
+	w.Write(b)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/enrichman/gofield"
+	"github.com/enrichman/gomuni"
+	"github.com/gorilla/mux"
+)
+
+type response struct {
+	Region *gomuni.Region `json:"region,omitempty"`
+	City   *gomuni.City   `json:"city,omitempty"`
+	Town   *gomuni.Town   `json:"town,omitempty"`
+
+	//Distance in kilometers of an Approximate Town, not containing the searched point
+	Distance    float64 `json:"distance,omitempty"`
+	Approximate bool    `json:"approximate,omitempty"`
+}
+
+//responseFields are the default fields of a response, without the children of the units
+const responseFields = "region{id,name},city{id,region_id,name,shortname,maincity},town{id,region_id,city_id,name}"
+
+//reduce selects the fields of each unit of the response, skipping the units not found
+func (res response) reduce(fields string) map[string]interface{} {
+	units := map[string]interface{}{}
+	if res.Region != nil {
+		units["region"] = res.Region
+	}
+	if res.City != nil {
+		units["city"] = res.City
+	}
+	if res.Town != nil {
+		units["town"] = res.Town
+	}
+
+	reduced := make(map[string]interface{})
+	for _, field := range gofield.Split(fields, ",") {
+		name, inner := splitField(field)
+		if unit, ok := units[name]; ok {
+			reduced[name] = reduce(unit, inner)
+		}
+	}
+	if res.Approximate {
+		reduced["distance"] = res.Distance
+		reduced["approximate"] = true
+	}
+	return reduced
+}
+
+//service serves the current dataset, swapped atomically by the reloader
+type service struct {
+	current    atomic.Value
+	topologies topologyCache
+	tiles      tileCache
+}
+
+func (s *service) dataset() *dataset {
+	return s.current.Load().(*dataset)
+}
+
+func (s *service) country() *gomuni.Country {
+	return s.dataset().country
+}
+
+func (s *service) swap(d *dataset) {
+	s.current.Store(d)
+}
+
+func (s *service) searchHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+
+	if q := vals.Get("q"); q != "" {
+		s.searchByNameHandler(w, r)
+		return
+	}
+
+	point, err := pointParam(vals)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	maxDistance, err := floatParam(vals, "max_distance")
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+
+	var at time.Time
+	if atStr := vals.Get("at"); atStr != "" {
+		if at, err = time.Parse(dateLayout, atStr); err != nil {
+			writeParameterError(w, &parameterError{"at", atStr, "invalid at date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	data := s.dataset()
+	country := data.country
+	if !at.IsZero() {
+		country = data.atlas.CountryAt(at)
+		if country == nil {
+			writeError(w, http.StatusUnprocessableEntity, codeDateNotCovered, "no boundaries valid at "+at.Format(dateLayout), map[string]string{"at": at.Format(dateLayout)})
+			return
+		}
+	}
+
+	location := country.Resolve(point)
+	res := response{Region: location.Region, City: location.City, Town: location.Town}
+
+	// outside every town, like offshore, fall back to the nearest one within max_distance km
+	if res.Town == nil && maxDistance > 0 {
+		if nearest := country.FindNearestTown(point, maxDistance); nearest.Town != nil {
+			res = response{
+				Region:      country.GetRegionByID(nearest.Town.RegionID),
+				City:        country.GetCityByID(nearest.Town.CityID),
+				Town:        nearest.Town,
+				Distance:    nearest.Distance,
+				Approximate: nearest.Approximate,
+			}
+		}
+	}
+
+	if res.Region == nil && res.Town == nil {
+		writeError(w, http.StatusUnprocessableEntity, codeOutsideCountry, "the point is outside the country", map[string]float64{"lat": point.Lat, "lng": point.Lng})
+		return
+	}
+	s.writeResponse(w, r, res)
+}
+
+//errMissingPoint is returned by pointParam when the request has no coordinates
+var errMissingPoint = &parameterError{"lat,lng", "", "missing lat and lng"}
+
+//pointParam reads the point of the latlng parameter, or of the lat and lng parameters
+func pointParam(vals url.Values) (gomuni.Point, error) {
+	lat := vals.Get("lat")
+	lng := vals.Get("lng")
+	if latlng, ok := vals["latlng"]; ok {
+		parts := strings.Split(latlng[0], ",")
+		if len(parts) != 2 {
+			return gomuni.Point{}, &parameterError{"latlng", latlng[0], "invalid latlng, expected lat,lng"}
+		}
+		lat, lng = parts[0], parts[1]
+	}
+
+	if lat == "" && lng == "" {
+		return gomuni.Point{}, errMissingPoint
+	}
+	latFloat, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil || latFloat < -90 || latFloat > 90 {
+		return gomuni.Point{}, &parameterError{"lat", lat, "invalid lat, expected a number between -90 and 90"}
+	}
+	lngFloat, err := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if err != nil || lngFloat < -180 || lngFloat > 180 {
+		return gomuni.Point{}, &parameterError{"lng", lng, "invalid lng, expected a number between -180 and 180"}
+	}
+	return gomuni.Point{Lat: latFloat, Lng: lngFloat}, nil
+}
+
+//floatParam reads a positive number parameter, 0 if missing
+func floatParam(vals url.Values, name string) (float64, error) {
+	value := vals.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, &parameterError{name, value, "invalid " + name + ", expected a positive number"}
+	}
+	return f, nil
+}
+
+//intParam reads a positive integer parameter, 0 if missing
+func intParam(vals url.Values, name string) (int, error) {
+	value := vals.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, &parameterError{name, value, "invalid " + name + ", expected a positive integer"}
+	}
+	return i, nil
+}
+
+func (s *service) countryHandler(w http.ResponseWriter, r *http.Request) {
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeCountry(s.country(), languages(r)), fields)
+}
+
+func (s *service) regionsHandler(w http.ResponseWriter, r *http.Request) {
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeRegions(s.country().Regions, languages(r)), fields)
+}
+
+func (s *service) regionIDHandler(w http.ResponseWriter, r *http.Request) {
+	region, ok := s.routeRegion(w, r)
+	if !ok {
+		return
+	}
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeRegion(region, languages(r)), fields)
+}
+
+func (s *service) regionCitiesHandler(w http.ResponseWriter, r *http.Request) {
+	region, ok := s.routeRegion(w, r)
+	if !ok {
+		return
+	}
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeCities(region.Cities, languages(r)), fields)
+}
+
+func (s *service) regionCityIDHandler(w http.ResponseWriter, r *http.Request) {
+	city, ok := s.routeCity(w, r)
+	if !ok {
+		return
+	}
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeCity(city, languages(r)), fields)
+}
+
+func (s *service) townsHandler(w http.ResponseWriter, r *http.Request) {
+	city, ok := s.routeCity(w, r)
+	if !ok {
+		return
+	}
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeTowns(city.Towns, languages(r)), fields)
+}
+
+func (s *service) regionCityTownIDHandler(w http.ResponseWriter, r *http.Request) {
+	town, ok := s.routeTown(w, r)
+	if !ok {
+		return
+	}
+	fields := r.URL.Query().Get("fields")
+	writeUnits(w, r, localizeTown(town, languages(r)), fields)
+}
+
+//routeRegion returns the Region of the region_id route variable, writing a 404 if not found
+func (s *service) routeRegion(w http.ResponseWriter, r *http.Request) (*gomuni.Region, bool) {
+	regionID := mux.Vars(r)["region_id"]
+	region := s.country().GetRegionByID(regionID)
+	if region == nil {
+		writeNotFound(w, "region_id", regionID)
+		return nil, false
+	}
+	return region, true
+}
+
+//routeCity returns the City of the region_id and city_id route variables, writing a 404 if not found
+func (s *service) routeCity(w http.ResponseWriter, r *http.Request) (*gomuni.City, bool) {
+	region, ok := s.routeRegion(w, r)
+	if !ok {
+		return nil, false
+	}
+	cityID := mux.Vars(r)["city_id"]
+	city := region.GetCityByID(cityID)
+	if city == nil {
+		writeNotFound(w, "city_id", cityID)
+		return nil, false
+	}
+	return city, true
+}
+
+//routeTown returns the Town of the region_id, city_id and town_id route variables, writing a 404 if not found
+func (s *service) routeTown(w http.ResponseWriter, r *http.Request) (*gomuni.Town, bool) {
+	city, ok := s.routeCity(w, r)
+	if !ok {
+		return nil, false
+	}
+	townID := mux.Vars(r)["town_id"]
+	town := city.GetTownByID(townID)
+	if town == nil {
+		writeNotFound(w, "town_id", townID)
+		return nil, false
+	}
+	return town, true
+}
+
+//searchByNameHandler returns the units matching the q parameter, the best limit ones
+func (s *service) searchByNameHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+
+	limit, err := intParam(vals, "limit")
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	if limit == 0 {
+		limit = 10
+	}
+	fields := vals.Get("fields")
+	if fields == "" {
+		fields = responseFields
+	}
+
+	langs := languages(r)
+	responses := make([]response, 0)
+	results := make([]map[string]interface{}, 0)
+	for _, m := range s.country().SearchByName(vals.Get("q"), limit) {
+		res := response{Region: m.Region, City: m.City, Town: m.Town}
+		result := res.localize(langs).reduce(fields)
+		result["level"] = m.Level
+		result["name"] = m.Name
+		result["score"] = m.Score
+		responses = append(responses, res)
+		results = append(results, result)
+	}
+
+	writeResults(w, r, responses, results)
+}
+
+//nearbyHandler returns the towns within radius km from the point, or the k nearest ones,
+//measuring the distance from their polygon or, with distance=centroid, from their centroid
+func (s *service) nearbyHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+
+	point, err := pointParam(vals)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	radius, err := floatParam(vals, "radius")
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	k, err := intParam(vals, "k")
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	if radius == 0 && k == 0 {
+		writeParameterError(w, &parameterError{"radius,k", "", "missing radius or k"})
+		return
+	}
+
+	mode := gomuni.PolygonDistance
+	switch vals.Get("distance") {
+	case "", "polygon":
+	case "centroid":
+		mode = gomuni.CentroidDistance
+	default:
+		writeParameterError(w, &parameterError{"distance", vals.Get("distance"), "invalid distance, expected polygon or centroid"})
+		return
+	}
+
+	country := s.country()
+	var towns []gomuni.TownDistance
+	if k > 0 {
+		towns = country.NearestTowns(point, k, mode)
+	} else {
+		towns = country.TownsWithinRadius(point, radius, mode)
+	}
+
+	fields := vals.Get("fields")
+	if fields == "" {
+		fields = responseFields
+	}
+	langs := languages(r)
+
+	responses := make([]response, 0, len(towns))
+	results := make([]map[string]interface{}, 0, len(towns))
+	for _, t := range towns {
+		// with both k and radius the nearest towns are limited to the radius
+		if radius > 0 && t.Distance > radius {
+			break
+		}
+		res := response{Region: country.GetRegionByID(t.Town.RegionID), City: country.GetCityByID(t.Town.CityID), Town: t.Town}
+		result := res.localize(langs).reduce(fields)
+		result["distance"] = t.Distance
+		responses = append(responses, res)
+		results = append(results, result)
+	}
+
+	writeResults(w, r, responses, results)
+}
+
+//townHandler returns the Town with the provided ISTAT code and its parents.
+//A retired code is redirected to the Town that took it over, following the successions,
+//or its successors are listed with a 300 Multiple Choices if the town was split.
+func (s *service) townHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["town_id"]
+	data := s.dataset()
+
+	if town := data.country.GetTownByID(id); town != nil {
+		region := data.country.GetRegionByID(town.RegionID)
+		res := response{Region: region, City: data.country.GetCityByID(town.CityID), Town: town}
+		s.writeResponse(w, r, res)
+		return
+	}
+
+	successions := data.successions.ResolveTownID(id)
+	if len(successions) == 0 {
+		writeNotFound(w, "town_id", id)
+		return
+	}
+	if len(successions) == 1 && data.country.GetTownByID(successions[0].ID) != nil {
+		path := "/towns/" + successions[0].ID
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+		// http.Redirect writes its own HTML body
+		w.Header().Del("Content-Type")
+		http.Redirect(w, r, path, http.StatusMovedPermanently)
+		return
+	}
+
+	w.WriteHeader(http.StatusMultipleChoices)
+	b, _ := json.Marshal(successions)
+	w.Write(b)
+}
+
+//cityHandler returns the City with the provided ISTAT code or sigla and its Region
+func (s *service) cityHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["city_id"]
+	country := s.country()
+
+	city := country.GetCityByID(id)
+	if city == nil {
+		city = country.GetCityByShortname(id)
+	}
+	if city == nil {
+		writeNotFound(w, "city_id", id)
+		return
+	}
+
+	s.writeResponse(w, r, response{Region: country.GetRegionByID(city.RegionID), City: city})
+}
+
+//writeResponse writes the response in the languages of the request, reduced to the requested fields or to the responseFields,
+//as the GeoJSON Feature of its deepest unit if requested
+func (s *service) writeResponse(w http.ResponseWriter, r *http.Request, res response) {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		fields = responseFields
+	}
+	reduced := res.localize(languages(r)).reduce(fields)
+	if wantsGeoJSON(r) {
+		writeGeoJSON(w, res.feature(reduced))
+		return
+	}
+	b, _ := json.Marshal(reduced)
+	w.Write(b)
+}
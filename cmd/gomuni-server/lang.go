@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+)
+
+//languages returns the preferred languages of the request: the ones of the lang parameter,
+//then the ones of the Accept-Language header sorted by their quality
+func languages(r *http.Request) []string {
+	langs := make([]string, 0)
+	for _, lang := range strings.Split(r.URL.Query().Get("lang"), ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+
+	type weighted struct {
+		lang    string
+		quality float64
+	}
+	accepted := make([]weighted, 0)
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		params := strings.Split(part, ";")
+		lang := strings.TrimSpace(params[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, p := range params[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			accepted = append(accepted, weighted{lang, quality})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].quality > accepted[j].quality })
+
+	for _, a := range accepted {
+		langs = append(langs, a.lang)
+	}
+	return langs
+}
+
+//The localize functions return the units with the Name in the first of the languages found, and the ones of their
+//cities and towns. The units are shared by all the requests and they are never modified: a unit is copied only
+//if its name or the name of one of its children changes.
+
+func localizeCountry(country *gomuni.Country, langs []string) *gomuni.Country {
+	if country == nil || len(langs) == 0 {
+		return country
+	}
+	regions, changed := localizedRegions(country.Regions, langs)
+	if !changed {
+		return country
+	}
+	localized := *country
+	localized.Regions = regions
+	return &localized
+}
+
+func localizeRegion(region *gomuni.Region, langs []string) *gomuni.Region {
+	if region == nil || len(langs) == 0 {
+		return region
+	}
+	name := region.LocalizedName(langs...)
+	cities, changed := localizedCities(region.Cities, langs)
+	if name == region.Name && !changed {
+		return region
+	}
+	localized := *region
+	localized.Name = name
+	localized.Cities = cities
+	return &localized
+}
+
+func localizeCity(city *gomuni.City, langs []string) *gomuni.City {
+	if city == nil || len(langs) == 0 {
+		return city
+	}
+	name := city.LocalizedName(langs...)
+	towns, changed := localizedTowns(city.Towns, langs)
+	if name == city.Name && !changed {
+		return city
+	}
+	localized := *city
+	localized.Name = name
+	localized.Towns = towns
+	return &localized
+}
+
+func localizeTown(town *gomuni.Town, langs []string) *gomuni.Town {
+	if town == nil || len(langs) == 0 {
+		return town
+	}
+	name := town.LocalizedName(langs...)
+	if name == town.Name {
+		return town
+	}
+	localized := *town
+	localized.Name = name
+	return &localized
+}
+
+func localizeRegions(regions []*gomuni.Region, langs []string) []*gomuni.Region {
+	localized, _ := localizedRegions(regions, langs)
+	return localized
+}
+
+func localizeCities(cities []*gomuni.City, langs []string) []*gomuni.City {
+	localized, _ := localizedCities(cities, langs)
+	return localized
+}
+
+func localizeTowns(towns []*gomuni.Town, langs []string) []*gomuni.Town {
+	localized, _ := localizedTowns(towns, langs)
+	return localized
+}
+
+//The localized functions return the localized units, or the same slice and false if no unit changes
+
+func localizedRegions(regions []*gomuni.Region, langs []string) ([]*gomuni.Region, bool) {
+	var localized []*gomuni.Region
+	for i, r := range regions {
+		if l := localizeRegion(r, langs); l != r {
+			if localized == nil {
+				localized = append([]*gomuni.Region{}, regions...)
+			}
+			localized[i] = l
+		}
+	}
+	if localized == nil {
+		return regions, false
+	}
+	return localized, true
+}
+
+func localizedCities(cities []*gomuni.City, langs []string) ([]*gomuni.City, bool) {
+	var localized []*gomuni.City
+	for i, c := range cities {
+		if l := localizeCity(c, langs); l != c {
+			if localized == nil {
+				localized = append([]*gomuni.City{}, cities...)
+			}
+			localized[i] = l
+		}
+	}
+	if localized == nil {
+		return cities, false
+	}
+	return localized, true
+}
+
+func localizedTowns(towns []*gomuni.Town, langs []string) ([]*gomuni.Town, bool) {
+	var localized []*gomuni.Town
+	for i, t := range towns {
+		if l := localizeTown(t, langs); l != t {
+			if localized == nil {
+				localized = append([]*gomuni.Town{}, towns...)
+			}
+			localized[i] = l
+		}
+	}
+	if localized == nil {
+		return towns, false
+	}
+	return localized, true
+}
+
+//localize returns the response with the localized units
+func (res response) localize(langs []string) response {
+	res.Region = localizeRegion(res.Region, langs)
+	res.City = localizeCity(res.City, langs)
+	res.Town = localizeTown(res.Town, langs)
+	return res
+}
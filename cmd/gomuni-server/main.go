@@ -1,155 +1,85 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
 	"os"
-	"strings"
 
-	"encoding/json"
-
-	"strconv"
-
-	"github.com/enrichman/gofield"
-	"github.com/enrichman/gomuni"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
 )
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	log.Println(".env file loaded")
-
-	regionFolder := os.Getenv("REGION_FOLDER")
-	cityFolder := os.Getenv("CITY_FOLDER")
-	townFolder := os.Getenv("TOWN_FOLDER")
-
-	log.Println("Loading folders:", regionFolder, cityFolder, townFolder)
-	country := gomuni.Load(regionFolder, cityFolder, townFolder)
-	log.Println("Country loaded")
-
-	log.Println("Loading handlers")
-	service := service{country}
-
-	router := mux.NewRouter()
-	router.HandleFunc("/search", service.searchHandler).Methods("GET")
-	router.HandleFunc("/country", service.countryHandler).Methods("GET")
-	router.HandleFunc("/country/regions", service.regionsHandler).Methods("GET")
-	router.HandleFunc("/country/regions/{region_id}", service.regionIDHandler).Methods("GET")
-	router.HandleFunc("/country/regions/{region_id}/cities", service.regionCitiesHandler).Methods("GET")
-	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}", service.regionCityIDHandler).Methods("GET")
-	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/towns", service.townsHandler).Methods("GET")
-	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/towns/{town_id}", service.regionCityTownIDHandler).Methods("GET")
-
-	log.Println("Ready")
-	log.Fatal(http.ListenAndServe(":8080", router))
-}
-
-type response struct {
-	Region *gomuni.Region `json:"region,omitempty"`
-	City   *gomuni.City   `json:"city,omitempty"`
-	Town   *gomuni.Town   `json:"town,omitempty"`
-}
-
-type service struct {
-	country *gomuni.Country
-}
-
-func (s *service) searchHandler(w http.ResponseWriter, r *http.Request) {
-	vals := r.URL.Query()
+var (
+	snapshotFile      = flag.String("snapshot", "", "load the country from a snapshot file instead of the shapefiles")
+	writeSnapshotFile = flag.String("write-snapshot", "", "write a snapshot of the loaded country to the file")
+	watchInterval     = flag.Duration("watch", 0, "reload the dataset when its files change, checking them at this interval (0 disables)")
+)
 
-	var lat string
-	var lng string
+func main() {
+	flag.Parse()
 
-	latlng, okLatLng := vals["latlng"]
-	if okLatLng {
-		latlng = strings.Split(latlng[0], ",")
+	src, err := newSource()
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	latArr, okLat := vals["lat"]
-	lngArr, okLng := vals["lng"]
-
-	if okLatLng && len(latlng) > 1 {
-		lat = latlng[0]
-		lng = latlng[1]
-	} else if okLat && okLng {
-		lat = latArr[0]
-		lng = lngArr[0]
+	data, err := loadDataset(src)
+	if err != nil {
+		log.Fatal(err)
 	}
+	log.Println("Country loaded, version", data.version)
 
-	var town *gomuni.Town
-	if lat != "" && lng != "" {
-		latFloat, _ := strconv.ParseFloat(lat, 64)
-		lngFloat, _ := strconv.ParseFloat(lng, 64)
-		point := gomuni.Point{Lat: latFloat, Lng: lngFloat}
-		town = s.country.FindTownByPoint(point)
+	if *writeSnapshotFile != "" {
+		if err := writeSnapshot(data.country, *writeSnapshotFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Snapshot written to", *writeSnapshotFile)
 	}
 
-	b, _ := json.Marshal(town)
-	w.Write(b)
-}
-
-func (s *service) countryHandler(w http.ResponseWriter, r *http.Request) {
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(s.country, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
-}
-
-func (s *service) regionsHandler(w http.ResponseWriter, r *http.Request) {
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(s.country.Regions, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
-}
-
-func (s *service) regionIDHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(s.country.GetRegionByID(vars["region_id"]), fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
-}
-
-func (s *service) regionCitiesHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	region := s.country.GetRegionByID(vars["region_id"])
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(region.Cities, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
-}
+	log.Println("Loading handlers")
+	service := &service{}
+	service.swap(data)
 
-func (s *service) regionCityIDHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	region := s.country.GetRegionByID(vars["region_id"])
-	city := region.GetCityByID(vars["city_id"])
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(city, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
-}
+	reloader := &reloader{source: src, service: service, token: os.Getenv("ADMIN_TOKEN")}
+	go reloader.handleSignals()
+	if *watchInterval > 0 {
+		go reloader.watch(*watchInterval)
+	}
 
-func (s *service) townsHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	region := s.country.GetRegionByID(vars["region_id"])
-	city := region.GetCityByID(vars["city_id"])
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(city.Towns, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
+	log.Println("Ready")
+	log.Fatal(http.ListenAndServe(":8080", newRouter(service, reloader)))
 }
 
-func (s *service) regionCityTownIDHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	region := s.country.GetRegionByID(vars["region_id"])
-	city := region.GetCityByID(vars["city_id"])
-	town := city.GetTownByID(vars["town_id"])
-	fields := r.URL.Query().Get("fields")
-	lightObj := gofield.Reduce(town, fields)
-	b, _ := json.Marshal(lightObj)
-	w.Write(b)
+//newRouter returns the routes of the service and of the reloader, answering with JSON errors
+//the unknown routes and the panics of the handlers
+func newRouter(s *service, r *reloader) http.Handler {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+
+	router.HandleFunc("/status", r.statusHandler).Methods("GET")
+	router.HandleFunc("/admin/reload", r.reloadHandler).Methods("POST")
+	router.HandleFunc("/search", s.searchHandler).Methods("GET")
+	router.HandleFunc("/search/batch", s.batchHandler).Methods("POST")
+	router.HandleFunc("/regions", s.bboxHandler("region")).Methods("GET")
+	router.HandleFunc("/regions/intersecting", s.intersectingHandler("region")).Methods("POST")
+	router.HandleFunc("/cities", s.bboxHandler("city")).Methods("GET")
+	router.HandleFunc("/cities/intersecting", s.intersectingHandler("city")).Methods("POST")
+	router.HandleFunc("/towns", s.bboxHandler("town")).Methods("GET")
+	router.HandleFunc("/towns/intersecting", s.intersectingHandler("town")).Methods("POST")
+	router.HandleFunc("/towns/nearby", s.nearbyHandler).Methods("GET")
+	router.HandleFunc("/towns/{town_id}", s.townHandler).Methods("GET")
+	router.HandleFunc("/cities/{city_id}", s.cityHandler).Methods("GET")
+	router.HandleFunc("/country", s.countryHandler).Methods("GET")
+	router.HandleFunc("/country/topojson", s.topologyHandler).Methods("GET")
+	router.HandleFunc("/tiles/{z}/{x}/{y}.mvt", s.tileHandler).Methods("GET")
+	router.HandleFunc("/country/regions", s.regionsHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}", s.regionIDHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/geometry", s.regionGeometryHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities", s.regionCitiesHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}", s.regionCityIDHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/geometry", s.regionCityGeometryHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/towns", s.townsHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/towns/{town_id}", s.regionCityTownIDHandler).Methods("GET")
+	router.HandleFunc("/country/regions/{region_id}/cities/{city_id}/towns/{town_id}/geometry", s.regionCityTownGeometryHandler).Methods("GET")
+
+	return recoverer(router)
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/enrichman/gomuni"
+)
+
+var errReloadInProgress = errors.New("reload already in progress")
+
+//dataset is a loaded Country, with the Atlas of its vintages, the successions of the town codes and its version
+type dataset struct {
+	country     *gomuni.Country
+	atlas       *gomuni.Atlas
+	successions *gomuni.Successions
+	version     string
+	loadedAt    time.Time
+}
+
+//loadDataset loads and validates the Country of the source
+func loadDataset(src source) (*dataset, error) {
+	version, err := src.version()
+	if err != nil {
+		return nil, err
+	}
+
+	country, err := src.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(country); err != nil {
+		return nil, err
+	}
+	if err := src.loadNames(country); err != nil {
+		return nil, err
+	}
+
+	atlas, err := src.loadAtlas(country)
+	if err != nil {
+		return nil, err
+	}
+
+	successions, err := src.loadSuccessions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataset{country: country, atlas: atlas, successions: successions, version: version, loadedAt: time.Now()}, nil
+}
+
+//validate checks that every Region of the Country has its Cities and every City its Towns
+func validate(country *gomuni.Country) error {
+	if len(country.Regions) == 0 {
+		return errors.New("invalid dataset: no regions")
+	}
+	for _, r := range country.Regions {
+		if len(r.Cities) == 0 {
+			return fmt.Errorf("invalid dataset: region %s has no cities", r.ID)
+		}
+		for _, c := range r.Cities {
+			if len(c.Towns) == 0 {
+				return fmt.Errorf("invalid dataset: city %s has no towns", c.ID)
+			}
+		}
+	}
+	return nil
+}
+
+//reloader builds a new dataset in background and swaps it in the service.
+//The requests already running keep using the Country they started with.
+type reloader struct {
+	source  source
+	service *service
+	token   string
+
+	mu            sync.Mutex
+	loading       bool
+	lastReload    time.Time
+	lastErr       error
+	failedVersion string
+}
+
+type reloadStatus struct {
+	Version    string     `json:"version"`
+	LoadedAt   time.Time  `json:"loaded_at"`
+	Reloading  bool       `json:"reloading"`
+	LastReload *time.Time `json:"last_reload,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+//reload loads the dataset and swaps it in the service if valid
+func (r *reloader) reload(trigger string) error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	return r.run(trigger)
+}
+
+//begin marks a reload as started, unless another one is running
+func (r *reloader) begin() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loading {
+		return errReloadInProgress
+	}
+	r.loading = true
+	return nil
+}
+
+//run loads the dataset of a reload started by begin and swaps it in the service if valid
+func (r *reloader) run(trigger string) error {
+	log.Println("Reloading dataset, triggered by", trigger)
+	d, err := loadDataset(r.source)
+
+	r.mu.Lock()
+	r.loading = false
+	r.lastReload = time.Now()
+	r.lastErr = err
+	r.failedVersion = ""
+	if err != nil {
+		r.failedVersion, _ = r.source.version()
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Println("Reload failed, keeping the current dataset:", err)
+		return err
+	}
+
+	r.service.swap(d)
+	log.Println("Dataset reloaded, version", d.version)
+	return nil
+}
+
+func (r *reloader) status() reloadStatus {
+	d := r.service.dataset()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := reloadStatus{
+		Version:   d.version,
+		LoadedAt:  d.loadedAt,
+		Reloading: r.loading,
+	}
+	if !r.lastReload.IsZero() {
+		lastReload := r.lastReload
+		status.LastReload = &lastReload
+	}
+	if r.lastErr != nil {
+		status.LastError = r.lastErr.Error()
+	}
+	return status
+}
+
+//handleSignals reloads the dataset on SIGHUP
+func (r *reloader) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		r.reload("SIGHUP")
+	}
+}
+
+//watch reloads the dataset when the files of the source change, checking them every interval
+func (r *reloader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		version, err := r.source.version()
+		if err != nil {
+			log.Println("Cannot check the dataset files:", err)
+			continue
+		}
+
+		r.mu.Lock()
+		failed := version == r.failedVersion
+		r.mu.Unlock()
+
+		if version != r.service.dataset().version && !failed {
+			r.reload("a change in " + strings.Join(r.source.paths(), ", "))
+		}
+	}
+}
+
+//authorized checks the bearer token of the request
+func (r *reloader) authorized(req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) == 1
+}
+
+//reloadHandler starts a reload in background, it is enabled only if an admin token is configured
+func (r *reloader) reloadHandler(w http.ResponseWriter, req *http.Request) {
+	if r.token == "" {
+		notFoundHandler(w, req)
+		return
+	}
+	if !r.authorized(req) {
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "missing or invalid bearer token", nil)
+		return
+	}
+
+	// the reload is started here, so that no other one can start before it and the status reports it
+	if err := r.begin(); err != nil {
+		writeError(w, http.StatusConflict, codeConflict, err.Error(), nil)
+		return
+	}
+	status := r.status()
+	go r.run("the admin endpoint")
+
+	w.WriteHeader(http.StatusAccepted)
+	b, _ := json.Marshal(status)
+	w.Write(b)
+}
+
+func (r *reloader) statusHandler(w http.ResponseWriter, req *http.Request) {
+	b, _ := json.Marshal(r.status())
+	w.Write(b)
+}
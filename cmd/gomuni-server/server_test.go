@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enrichman/gomuni"
+	"github.com/enrichman/gomuni/internal/fixture"
+)
+
+//newTestRouter serves the fixture, valid from 2017, where the retired town 001999 became Torino
+//and 001998 was split in Torino and Moncalieri
+func newTestRouter(r *reloader) http.Handler {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	successions := gomuni.NewSuccessions()
+	successions.Add("001999", gomuni.TownSuccession{ID: "001272", Name: "Torino", Event: gomuni.EventExtinction})
+	successions.Add("001998", gomuni.TownSuccession{ID: "001272", Name: "Torino", Event: gomuni.EventExtinction})
+	successions.Add("001998", gomuni.TownSuccession{ID: "001156", Name: "Moncalieri", Event: gomuni.EventExtinction})
+
+	validFrom := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &service{}
+	s.swap(&dataset{
+		country:     country,
+		atlas:       gomuni.NewAtlas(gomuni.Edition{ValidFrom: validFrom, Country: country}),
+		successions: successions,
+		version:     "test",
+	})
+	if r == nil {
+		r = &reloader{}
+	}
+	r.service = s
+	return newRouter(s, r)
+}
+
+const piemontePolygon = `{"type": "Polygon", "coordinates": [[[7.2, 44.5], [7.4, 44.5], [7.4, 44.7], [7.2, 44.7], [7.2, 44.5]]]}`
+
+func Test_routes(t *testing.T) {
+	router := newTestRouter(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		// code is the code of the error, or a fragment of the body if the request succeeds
+		code string
+	}{
+		{"status", "GET", "/status", "", 200, `"version":"test"`},
+
+		{"search", "GET", "/search?lat=45&lng=7.5", "", 200, `"id":"001272"`},
+		{"search latlng", "GET", "/search?latlng=45,8.5", "", 200, `"id":"001156"`},
+		{"search malformed lat", "GET", "/search?lat=abc&lng=7.5", "", 400, codeInvalidParameter},
+		{"search malformed latlng", "GET", "/search?latlng=45", "", 400, codeInvalidParameter},
+		{"search lat out of range", "GET", "/search?lat=95&lng=7.5", "", 400, codeInvalidParameter},
+		{"search missing lng", "GET", "/search?lat=45", "", 400, codeInvalidParameter},
+		{"search without point", "GET", "/search", "", 400, codeInvalidParameter},
+		{"search outside Italy", "GET", "/search?lat=41.9&lng=12.5", "", 422, codeOutsideCountry},
+		{"search in San Marino", "GET", "/search?lat=45&lng=11", "", 422, codeOutsideCountry},
+		{"search nearest", "GET", "/search?lat=45&lng=11&max_distance=50", "", 200, `"approximate":true`},
+		{"search malformed max_distance", "GET", "/search?lat=45&lng=11&max_distance=far", "", 400, codeInvalidParameter},
+		{"search at", "GET", "/search?lat=45&lng=7.5&at=2018-05-31", "", 200, `"id":"001272"`},
+		{"search before the first edition", "GET", "/search?lat=45&lng=7.5&at=2010-05-31", "", 422, codeDateNotCovered},
+		{"search malformed at", "GET", "/search?lat=45&lng=7.5&at=31/05/2018", "", 400, codeInvalidParameter},
+		{"search by name", "GET", "/search?q=moncalieri", "", 200, `"name":"Moncalieri"`},
+		{"search by name malformed limit", "GET", "/search?q=moncalieri&limit=all", "", 400, codeInvalidParameter},
+
+		{"batch", "POST", "/search/batch", `[{"id": 1, "lat": 45, "lng": 7.5}, {"id": 2, "lat": 0, "lng": 0}]`, 200, `"code":"outside_country"`},
+
+		{"regions bbox", "GET", "/regions?bbox=7,44,8,45", "", 200, `"name":"Piemonte"`},
+		{"regions malformed bbox", "GET", "/regions?bbox=7,44,8", "", 400, codeInvalidParameter},
+		{"regions intersecting", "POST", "/regions/intersecting", piemontePolygon, 200, `"name":"Piemonte"`},
+		{"regions intersecting malformed", "POST", "/regions/intersecting", `{"type": "Point"}`, 400, codeInvalidBody},
+		{"regions intersecting too large", "POST", "/regions/intersecting", strings.Repeat(" ", maxAreaBody+1), 413, codeInvalidBody},
+		{"cities bbox", "GET", "/cities?bbox=10.5,44.5,11.5,45.5", "", 200, `"shortname":"RN"`},
+		{"cities reversed bbox", "GET", "/cities?bbox=11.5,45.5,10.5,44.5", "", 400, codeInvalidParameter},
+		{"cities intersecting", "POST", "/cities/intersecting", piemontePolygon, 200, `"shortname":"TO"`},
+		{"towns bbox", "GET", "/towns?bbox=8.2,44.5,8.4,44.7", "", 200, `"id":"001156"`},
+		{"towns without bbox", "GET", "/towns", "", 400, codeInvalidParameter},
+		{"towns intersecting", "POST", "/towns/intersecting", piemontePolygon, 200, `"id":"001272"`},
+		{"towns intersecting empty", "POST", "/towns/intersecting", "", 400, codeInvalidBody},
+
+		{"nearby", "GET", "/towns/nearby?lat=45&lng=7.5&k=1", "", 200, `"id":"001272"`},
+		{"nearby radius", "GET", "/towns/nearby?lat=45&lng=7.9&radius=40&distance=centroid", "", 200, `"id":"001272"`},
+		{"nearby without radius", "GET", "/towns/nearby?lat=45&lng=7.5", "", 400, codeInvalidParameter},
+		{"nearby malformed k", "GET", "/towns/nearby?lat=45&lng=7.5&k=-1", "", 400, codeInvalidParameter},
+		{"nearby malformed distance", "GET", "/towns/nearby?lat=45&lng=7.5&k=1&distance=road", "", 400, codeInvalidParameter},
+
+		{"town", "GET", "/towns/001272", "", 200, `"name":"Torino"`},
+		{"town retired", "GET", "/towns/001999", "", 301, ""},
+		{"town split", "GET", "/towns/001998", "", 300, `"id":"001156"`},
+		{"town unknown", "GET", "/towns/000000", "", 404, codeNotFound},
+		{"city", "GET", "/cities/001", "", 200, `"shortname":"TO"`},
+		{"city by shortname", "GET", "/cities/rn", "", 200, `"name":"Rimini"`},
+		{"city unknown", "GET", "/cities/XX", "", 404, codeNotFound},
+
+		{"country", "GET", "/country?fields=regions{id}", "", 200, `"id":"8"`},
+		{"topojson", "GET", "/country/topojson", "", 200, `"type":"Topology"`},
+		{"topojson without quantization", "GET", "/country/topojson?quantization=0", "", 200, `"arcs":[[[8.0`},
+		{"topojson malformed quantization", "GET", "/country/topojson?quantization=1", "", 400, codeInvalidParameter},
+		{"topojson quantization not a power of ten", "GET", "/country/topojson?quantization=12345", "", 400, codeInvalidParameter},
+		{"topojson quantization too large", "GET", "/country/topojson?quantization=10000000", "", 400, codeInvalidParameter},
+		{"topojson simplified", "GET", "/country/topojson?simplify=medium", "", 200, `"type":"Topology"`},
+		{"tile malformed zoom", "GET", "/tiles/23/0/0.mvt", "", 400, codeInvalidParameter},
+		{"tile outside the zoom", "GET", "/tiles/2/4/0.mvt", "", 400, codeInvalidParameter},
+		{"tile malformed y", "GET", "/tiles/2/0/north.mvt", "", 400, codeInvalidParameter},
+		{"topojson malformed simplify", "GET", "/country/topojson?simplify=-1", "", 400, codeInvalidParameter},
+		{"topojson simplify in metres", "GET", "/country/topojson?simplify=250", "", 400, codeInvalidParameter},
+		{"regions", "GET", "/country/regions?fields=id", "", 200, `"id":"1"`},
+		{"region", "GET", "/country/regions/8?fields=name", "", 200, `"name":"Emilia-Romagna"`},
+		{"region unknown", "GET", "/country/regions/42", "", 404, codeNotFound},
+		{"region geometry", "GET", "/country/regions/8/geometry", "", 200, `]],[[10.9`},
+		{"region unknown geometry", "GET", "/country/regions/42/geometry", "", 404, codeNotFound},
+		{"region simplified geometry", "GET", "/country/regions/8/geometry?simplify=coarse", "", 200, `]],[[10.9`},
+		{"region simplified geometry in metres", "GET", "/country/regions/8/geometry?simplify=250&simplify_method=visvalingam", "", 200, `"type":"MultiPolygon"`},
+		{"region malformed simplify", "GET", "/country/regions/8/geometry?simplify=rough", "", 400, codeInvalidParameter},
+		{"region malformed simplify method", "GET", "/country/regions/8/geometry?simplify=fine&simplify_method=random", "", 400, codeInvalidParameter},
+		{"region cities", "GET", "/country/regions/1/cities?fields=shortname", "", 200, `"shortname":"TO"`},
+		{"region unknown cities", "GET", "/country/regions/42/cities", "", 404, codeNotFound},
+		{"region city", "GET", "/country/regions/1/cities/1?fields=shortname", "", 200, `"shortname":"TO"`},
+		{"region city unknown", "GET", "/country/regions/1/cities/99", "", 404, codeNotFound},
+		{"region city geometry", "GET", "/country/regions/1/cities/1/geometry", "", 200, `"type":"MultiPolygon"`},
+		{"region city towns", "GET", "/country/regions/1/cities/1/towns?fields=id", "", 200, `"id":"001156"`},
+		{"region city unknown towns", "GET", "/country/regions/8/cities/1/towns", "", 404, codeNotFound},
+		{"region city town", "GET", "/country/regions/1/cities/1/towns/001272?fields=name", "", 200, `"name":"Torino"`},
+		{"region city town unknown", "GET", "/country/regions/1/cities/1/towns/099014", "", 404, codeNotFound},
+		{"region city town geometry", "GET", "/country/regions/1/cities/1/towns/001156/geometry", "", 200, `"coordinates":[[[[8.0`},
+		{"region city town simplified geometry", "GET", "/country/regions/1/cities/1/towns/001156/geometry?simplify=fine", "", 200, `"coordinates":[[[[8.0`},
+		{"region city town unknown geometry", "GET", "/country/regions/1/cities/1/towns/099014/geometry", "", 404, codeNotFound},
+
+		{"unknown route", "GET", "/provinces", "", 404, codeNotFound},
+		{"wrong method", "DELETE", "/search", "", 404, codeNotFound},
+		{"reload disabled", "POST", "/admin/reload", "", 404, codeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code == http.StatusMovedPermanently {
+				if location := rec.Header().Get("Location"); location != "/towns/001272" {
+					t.Errorf("redirected to %q", location)
+				}
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type %q, want application/json", ct)
+			}
+
+			if rec.Code >= 400 {
+				var apiErr apiError
+				if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+					t.Fatalf("invalid error %s: %v", rec.Body, err)
+				}
+				if apiErr.Code != tt.code || apiErr.Message == "" {
+					t.Errorf("error %+v, want code %s", apiErr, tt.code)
+				}
+				return
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Fatalf("invalid JSON %s", rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.code) {
+				t.Errorf("body %s, want %s", rec.Body, tt.code)
+			}
+		})
+	}
+}
+
+func Test_geometryField(t *testing.T) {
+	router := newTestRouter(nil)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/search?lat=45&lng=8.5&fields=town{id,geometry{type}}", `{"town":{"geometry":{"type":"MultiPolygon"},"id":"001156"}}`},
+		{"/country/regions/1/cities/1?fields=shortname,towns{id,geometry{type}}", `{"shortname":"TO","towns":[{"geometry":{"type":"MultiPolygon"},"id":"001272"},{"geometry":{"type":"MultiPolygon"},"id":"001156"}]}`},
+		{"/country?fields=regions{id,cities{geometry{type}}}", `{"regions":[{"cities":[{"geometry":{"type":"MultiPolygon"}}],"id":"1"},{"cities":[{"geometry":{"type":"MultiPolygon"}}],"id":"8"}]}`},
+		{"/country/regions/1?fields=name", `{"name":"Piemonte"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_topology(t *testing.T) {
+	router := newTestRouter(nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/country/topojson?quantization=1000&fields=name,city_id", nil))
+
+	var topology gomuni.Topology
+	if err := json.Unmarshal(rec.Body.Bytes(), &topology); err != nil {
+		t.Fatalf("invalid TopoJSON %s", rec.Body)
+	}
+	if topology.Transform == nil || topology.Transform.Scale[0] <= 0 || len(topology.Arcs) != 5 {
+		t.Errorf("unexpected transform %+v", topology.Transform)
+	}
+	towns := topology.Objects[gomuni.TopologyTowns].Geometries
+	if len(towns) != 3 || len(towns[2].Properties) != 2 || towns[2].Properties["city_id"] != "99" {
+		t.Errorf("unexpected towns %+v", towns)
+	}
+	if regions := topology.Objects[gomuni.TopologyRegions].Geometries; len(regions) != 2 || len(regions[0].Properties) != 1 {
+		t.Errorf("unexpected regions %+v", regions)
+	}
+}
+
+func Test_topologyCache(t *testing.T) {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &dataset{country: country}
+	var c topologyCache
+
+	// the concurrent requests of the same options share the Topology
+	topologies := make(chan *gomuni.Topology, 4)
+	for i := 0; i < cap(topologies); i++ {
+		go func() { topologies <- c.get(data, tileTopology) }()
+	}
+	tiles := <-topologies
+	for i := 1; i < cap(topologies); i++ {
+		if topology := <-topologies; topology != tiles {
+			t.Fatal("the Topology is computed twice")
+		}
+	}
+
+	// a full cache drops its oldest options, but the ones of the tiles
+	first := c.get(data, gomuni.TopologyOptions{Quantization: 10})
+	for q := 100; q <= maxQuantization; q *= 10 {
+		c.get(data, gomuni.TopologyOptions{Quantization: q})
+	}
+	if len(c.entries) != maxTopologies {
+		t.Errorf("%d topologies cached, want %d", len(c.entries), maxTopologies)
+	}
+	if c.get(data, tileTopology) != tiles {
+		t.Error("the Topology of the tiles is dropped")
+	}
+	if c.get(data, gomuni.TopologyOptions{Quantization: 10}) == first {
+		t.Error("the oldest Topology is kept")
+	}
+}
+
+func Test_simplifiedGeometry(t *testing.T) {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &service{}
+	s.swap(&dataset{country: country})
+	router := newRouter(s, &reloader{service: s})
+
+	// a tolerance in metres simplifies the unit alone, a named level the whole Country once
+	for _, tt := range []struct {
+		path       string
+		topologies int
+	}{
+		{"/country/regions/8/geometry?simplify=250", 0},
+		{"/country/regions/8/geometry?simplify=251", 0},
+		{"/country/regions/8/geometry?simplify=medium", 1},
+		{"/country/regions/1/cities/1/towns/001272/geometry?simplify=medium", 1},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"type":"MultiPolygon"`) {
+			t.Errorf("%s: status %d, %s", tt.path, rec.Code, rec.Body)
+		}
+		if n := len(s.topologies.entries); n != tt.topologies {
+			t.Errorf("%s: %d topologies cached, want %d", tt.path, n, tt.topologies)
+		}
+	}
+}
+
+func Test_localizedUnits(t *testing.T) {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := country.LoadNames(strings.NewReader("level,id,lang,name\ntown,001156,de,Moncalier\n")); err != nil {
+		t.Fatal(err)
+	}
+	s := &service{}
+	s.swap(&dataset{country: country})
+	router := newRouter(s, &reloader{service: s})
+
+	tests := []struct {
+		path, acceptLanguage string
+	}{
+		{"/country?lang=de&fields=regions{cities{towns{name}}}", ""},
+		{"/country/regions/1?fields=cities{towns{name}}", "de"},
+		{"/country/regions/1/cities?lang=de&fields=towns{name}", ""},
+		{"/country/regions/1/cities/1?lang=de&fields=towns{name}", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if body := rec.Body.String(); !strings.Contains(body, `"name":"Moncalier"`) || !strings.Contains(body, `"name":"Torino"`) {
+			t.Errorf("%s: the nested towns are not localized: %s", tt.path, body)
+		}
+	}
+
+	// the shared units are not modified
+	if name := country.GetTownByID("001156").Name; name != "Moncalieri" {
+		t.Errorf("the Name of the shared town is %q", name)
+	}
+}
+
+func Test_tiles(t *testing.T) {
+	s := &service{}
+	router := newTestRouter(nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/tiles/0/0/0.mvt", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != mvtType || rec.Body.Len() == 0 {
+		t.Fatalf("unexpected tile: status %d, %q, %d bytes", rec.Code, rec.Header().Get("Content-Type"), rec.Body.Len())
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("Moncalieri")) {
+		t.Errorf("the name of Moncalieri is not in the tile")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/tiles/4/0/0.mvt", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("unexpected empty tile: status %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	// the cached tiles are dropped with their dataset
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topology := country.Topology(gomuni.TopologyOptions{})
+	data := &dataset{country: country}
+	tile := s.tiles.get(data, topology, 0, 0, 0)
+	if cached := s.tiles.get(data, nil, 0, 0, 0); !bytes.Equal(cached, tile) {
+		t.Errorf("the tile is not cached")
+	}
+	reloaded := &dataset{country: country}
+	if fresh := s.tiles.get(reloaded, topology, 0, 0, 0); !bytes.Equal(fresh, tile) || s.tiles.data != reloaded || len(s.tiles.tiles) != 1 {
+		t.Errorf("the tiles of the old dataset are kept")
+	}
+
+	// a full cache drops the least recently used tile
+	for x := 0; x < maxTiles-1; x++ {
+		s.tiles.get(reloaded, topology, 12, x, 0)
+	}
+	s.tiles.get(reloaded, topology, 0, 0, 0)
+	s.tiles.get(reloaded, topology, 12, maxTiles-1, 0)
+	_, evicted := s.tiles.tiles[[3]int{12, 0, 0}]
+	_, kept := s.tiles.tiles[[3]int{0, 0, 0}]
+	if len(s.tiles.tiles) != maxTiles || s.tiles.order.Len() != maxTiles || evicted || !kept {
+		t.Errorf("unexpected eviction: %d tiles, the oldest one kept %v, the used one kept %v", len(s.tiles.tiles), evicted, kept)
+	}
+}
+
+func Test_parameterErrorDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter(nil).ServeHTTP(rec, httptest.NewRequest("GET", "/search?lat=45&lng=seven", nil))
+
+	var apiErr struct {
+		Details map[string]string
+	}
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Details["parameter"] != "lng" || apiErr.Details["value"] != "seven" {
+		t.Errorf("unexpected details %s", rec.Body)
+	}
+}
+
+func Test_batchNDJSON(t *testing.T) {
+	// the malformed line gets its own error, the lines after it are resolved
+	body := "{\"id\": \"a\", \"lat\": 45, \"lng\": 7.5}\n{\"id\": \"b\", \"lat\": 45}\n{\"id\": \"c\", \"lat\": 4\n\n{\"id\": \"d\", \"lat\": 45, \"lng\": 8.5}"
+	rec := httptest.NewRecorder()
+	newTestRouter(nil).ServeHTTP(rec, httptest.NewRequest("POST", "/search/batch", strings.NewReader(body)))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type %q, want application/x-ndjson", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"id":"001272"`) || !strings.Contains(lines[3], `"id":"001156"`) {
+		t.Fatalf("unexpected results %s", rec.Body)
+	}
+	if want := `{"error":{"code":"invalid_parameter","message":"missing lat or lng"},"id":"b"}`; lines[1] != want {
+		t.Errorf("got %s, want %s", lines[1], want)
+	}
+	if !strings.Contains(lines[2], `"code":"invalid_body"`) {
+		t.Errorf("got %s, want an invalid_body error", lines[2])
+	}
+}
+
+func Test_reloadErrors(t *testing.T) {
+	r := &reloader{token: "secret", loading: true}
+	router := newTestRouter(r)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		code          string
+	}{
+		{"without token", "", 401, codeUnauthorized},
+		{"wrong token", "Bearer guess", 401, codeUnauthorized},
+		{"in progress", "Bearer secret", 409, codeConflict},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/admin/reload", nil)
+		req.Header.Set("Authorization", tt.authorization)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var apiErr apiError
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if rec.Code != tt.status || apiErr.Code != tt.code {
+			t.Errorf("%s: got %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+}
+
+func Test_reload(t *testing.T) {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "gomuni.snap")
+	if err := writeSnapshot(country, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &reloader{source: source{snapshot: snapshot}, token: "secret"}
+	router := newTestRouter(r)
+
+	req := httptest.NewRequest("POST", "/admin/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var status reloadStatus
+	json.Unmarshal(rec.Body.Bytes(), &status)
+	if rec.Code != http.StatusAccepted || !status.Reloading || status.Version != "test" {
+		t.Fatalf("got %d %s, want 202 with the reload running", rec.Code, rec.Body)
+	}
+
+	for deadline := time.Now().Add(10 * time.Second); r.status().Reloading; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the reload did not finish")
+		}
+	}
+	want, _ := r.source.version()
+	if status := r.status(); status.Version != want || status.LastReload == nil || status.LastError != "" {
+		t.Errorf("got %+v after the reload, want version %s", status, want)
+	}
+
+	if err := r.begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload("SIGHUP"); err != errReloadInProgress {
+		t.Errorf("reload() during another reload error = %v, want errReloadInProgress", err)
+	}
+}
+
+func Test_recoverer(t *testing.T) {
+	handler := recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var country *gomuni.Country
+		country.GetRegionByID("1")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var apiErr apiError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusInternalServerError || apiErr.Code != codeInternal {
+		t.Errorf("got %d %s, want 500 %s", rec.Code, rec.Body, codeInternal)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", ct)
+	}
+}
+
+func Test_geoJSON(t *testing.T) {
+	router := newTestRouter(nil)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		// feature is true if the route returns a single Feature instead of a FeatureCollection
+		feature bool
+		ids     []string
+	}{
+		{"GET", "/search?lat=45&lng=7.5", "", true, []string{"001272"}},
+		{"GET", "/search?q=rimini&limit=1", "", false, nil},
+		{"GET", "/towns/001156", "", true, []string{"001156"}},
+		{"GET", "/cities/RN", "", true, []string{"99"}},
+		{"GET", "/regions?bbox=7,44,12,46", "", false, []string{"1", "8"}},
+		{"POST", "/cities/intersecting", piemontePolygon, false, []string{"1"}},
+		{"GET", "/towns/nearby?lat=45&lng=7.5&k=2", "", false, []string{"001272", "001156"}},
+		{"POST", "/search/batch", `[{"id": "a", "lat": 45, "lng": 8.5}, {"id": "b"}]`, false, []string{"001156", ""}},
+		{"GET", "/country", "", false, []string{"1", "8"}},
+		{"GET", "/country/regions", "", false, []string{"1", "8"}},
+		{"GET", "/country/regions/8", "", true, []string{"8"}},
+		{"GET", "/country/regions/1/cities", "", false, []string{"1"}},
+		{"GET", "/country/regions/1/cities/1", "", true, []string{"1"}},
+		{"GET", "/country/regions/1/cities/1/towns", "", false, []string{"001272", "001156"}},
+		{"GET", "/country/regions/1/cities/1/towns/001272", "", true, []string{"001272"}},
+	}
+
+	for _, tt := range tests {
+		for _, byHeader := range []bool{false, true} {
+			path := tt.path
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.body))
+			if byHeader {
+				req.Header.Set("Accept", "application/json;q=0.5, application/geo+json")
+			} else {
+				req.URL.RawQuery += "&format=geojson"
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if ct := rec.Header().Get("Content-Type"); rec.Code != 200 || ct != geoJSONType {
+				t.Errorf("%s: got %d %s, want 200 %s", path, rec.Code, ct, geoJSONType)
+				continue
+			}
+
+			var obj struct {
+				gomuni.Feature
+				Features []gomuni.Feature `json:"features"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &obj); err != nil {
+				t.Fatalf("%s: invalid GeoJSON %s", path, rec.Body)
+			}
+
+			features := obj.Features
+			if tt.feature {
+				if obj.Type != "Feature" {
+					t.Errorf("%s: got a %s, want a Feature", path, obj.Type)
+				}
+				features = []gomuni.Feature{obj.Feature}
+			} else if obj.Type != "FeatureCollection" {
+				t.Errorf("%s: got a %s, want a FeatureCollection", path, obj.Type)
+			}
+			if tt.ids == nil {
+				if len(features) != 1 || features[0].Properties["name"] != "Rimini" {
+					t.Errorf("%s: unexpected features %s", path, rec.Body)
+				}
+				continue
+			}
+			ids := make([]string, len(features))
+			for i, f := range features {
+				ids[i] = f.ID
+				if f.ID != "" && (f.Geometry == nil || f.Geometry.Type != "MultiPolygon" || f.Properties == nil) {
+					t.Errorf("%s: invalid feature %s", path, rec.Body)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.ids, ",") {
+				t.Errorf("%s: got the features %v, want %v", path, ids, tt.ids)
+			}
+		}
+	}
+
+	// the errors are not GeoJSON
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/towns/000000?format=geojson", nil))
+	if ct := rec.Header().Get("Content-Type"); rec.Code != 404 || ct != "application/json" {
+		t.Errorf("got %d %s, want a 404 with JSON", rec.Code, ct)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/enrichman/gomuni"
+	"github.com/gorilla/mux"
+)
+
+//mvtType is the media type of the Mapbox Vector Tiles
+const mvtType = "application/vnd.mapbox-vector-tile"
+
+//maxTileZoom is the deepest zoom of the tiles, about 2 centimetres for each point of a tile
+const maxTileZoom = 22
+
+//maxTiles is the number of tiles cached for the current dataset
+const maxTiles = 4096
+
+//tileTopology are the options of the Topology of the tiles, that simplify its arcs for each zoom
+var tileTopology = gomuni.TopologyOptions{}
+
+//tileEntry is an encoded tile z/x/y
+type tileEntry struct {
+	key  [3]int
+	tile []byte
+}
+
+//tileCache keeps the encoded tiles of the current dataset, dropping the least recently used ones
+type tileCache struct {
+	mu    sync.Mutex
+	data  *dataset
+	tiles map[[3]int]*list.Element
+	//order are the cached tileEntry, the most recently used first
+	order *list.List
+}
+
+//get returns the tile z/x/y of the dataset, encoding it from the Topology if not cached
+func (c *tileCache) get(data *dataset, topology *gomuni.Topology, z, x, y int) []byte {
+	key := [3]int{z, x, y}
+	c.mu.Lock()
+	if c.data != data {
+		c.data = data
+		c.tiles = make(map[[3]int]*list.Element)
+		c.order = list.New()
+	}
+	if e, ok := c.tiles[key]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*tileEntry).tile
+	}
+	c.mu.Unlock()
+
+	// the tile is encoded without the lock, two requests of the same tile can both encode it
+	tile := topology.MarshalMVT(z, x, y)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.tiles[key]; c.data == data && !ok {
+		c.tiles[key] = c.order.PushFront(&tileEntry{key, tile})
+		if c.order.Len() > maxTiles {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.tiles, oldest.Value.(*tileEntry).key)
+		}
+	}
+	return tile
+}
+
+//tileParam reads the z, x or y route variable of a tile, between 0 and max
+func tileParam(vars map[string]string, name string, max int) (int, error) {
+	value := vars[name]
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 || i > max {
+		return 0, &parameterError{name, value, "invalid " + name + ", expected an integer between 0 and " + strconv.Itoa(max)}
+	}
+	return i, nil
+}
+
+//tileHandler returns the tile z/x/y as a Mapbox Vector Tile with the regions, cities and towns layers.
+//The boundaries are simplified for the zoom and clipped to the tile, an empty tile has no content.
+func (s *service) tileHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	z, err := tileParam(vars, "z", maxTileZoom)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	x, err := tileParam(vars, "x", 1<<uint(z)-1)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	y, err := tileParam(vars, "y", 1<<uint(z)-1)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+
+	data := s.dataset()
+	tile := s.tiles.get(data, s.topologies.get(data, tileTopology), z, x, y)
+
+	w.Header().Set("Content-Type", mvtType)
+	w.Write(tile)
+}
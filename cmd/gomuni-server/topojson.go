@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/enrichman/gomuni"
+)
+
+//defaultQuantization snaps the points to a grid of about 10 meters over Italy
+const defaultQuantization = 100000
+
+//maxQuantization is the largest power of ten accepted by the quantization parameter
+const maxQuantization = 1000000
+
+//maxTopologies is the number of options, like quantizations and tolerances, cached for the current dataset
+const maxTopologies = 4
+
+//topologyEntry is a cached Topology, computed once by the first request of its options
+type topologyEntry struct {
+	once     sync.Once
+	topology *gomuni.Topology
+}
+
+//topologyCache keeps the topologies of the current dataset, the shared arcs are computed once for each options
+type topologyCache struct {
+	mu      sync.Mutex
+	data    *dataset
+	entries map[gomuni.TopologyOptions]*topologyEntry
+	//order are the cached options, the oldest first
+	order []gomuni.TopologyOptions
+}
+
+//get returns the Topology of the dataset, computing it if not cached. It is computed without the lock:
+//the requests of other options are not blocked, the ones of the same options wait for the same Topology.
+func (c *topologyCache) get(data *dataset, opts gomuni.TopologyOptions) *gomuni.Topology {
+	c.mu.Lock()
+	if c.data != data {
+		c.data = data
+		c.entries = make(map[gomuni.TopologyOptions]*topologyEntry)
+		c.order = nil
+	}
+	entry, ok := c.entries[opts]
+	if !ok {
+		if len(c.entries) >= maxTopologies {
+			c.evict()
+		}
+		entry = &topologyEntry{}
+		c.entries[opts] = entry
+		c.order = append(c.order, opts)
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.topology = data.country.Topology(opts)
+	})
+	return entry.topology
+}
+
+//evict drops the oldest options, but the ones of the tiles that are needed by every tile
+func (c *topologyCache) evict() {
+	for i, opts := range c.order {
+		if opts != tileTopology {
+			delete(c.entries, opts)
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return
+		}
+	}
+}
+
+//quantizationParam reads the quantization parameter, 0 or a power of ten up to maxQuantization
+func quantizationParam(vals url.Values) (int, error) {
+	if vals.Get("quantization") == "" {
+		return defaultQuantization, nil
+	}
+	q, err := intParam(vals, "quantization")
+	valid := err == nil && q == 0
+	for p := 10; p <= maxQuantization; p *= 10 {
+		valid = valid || (err == nil && q == p)
+	}
+	if !valid {
+		return 0, &parameterError{"quantization", vals.Get("quantization"), "invalid quantization, expected 0 or a power of ten up to " + strconv.Itoa(maxQuantization)}
+	}
+	return q, nil
+}
+
+//topologyHandler returns the regions, cities and towns as a TopoJSON Topology, sharing the arcs of their boundaries.
+//The coordinates are quantized with the quantization parameter, a power of ten or 0 keeping them as they are,
+//and the arcs are simplified with the named levels of the simplify parameter.
+func (s *service) topologyHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+
+	quantization, err := quantizationParam(vals)
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+	tolerance, method, shared, err := simplifyParams(vals)
+	if err == nil && tolerance > 0 && !shared {
+		// each tolerance is a topology of the whole Country, only the named levels are cached
+		err = &parameterError{"simplify", vals.Get("simplify"), "invalid simplify, expected fine, medium or coarse"}
+	}
+	if err != nil {
+		writeParameterError(w, err)
+		return
+	}
+
+	data := s.dataset()
+	topology := s.topologies.get(data, gomuni.TopologyOptions{Quantization: quantization, Tolerance: tolerance, Method: method})
+
+	fields := vals.Get("fields")
+	langs := languages(r)
+	if fields == "" && len(langs) == 0 {
+		b, _ := json.Marshal(topology)
+		w.Write(b)
+		return
+	}
+
+	// the cached topology is shared, the properties are replaced in a copy
+	reduced := *topology
+	reduced.Objects = make(map[string]gomuni.TopologyObject, len(topology.Objects))
+	for layer, obj := range topology.Objects {
+		geometries := make([]gomuni.TopologyGeometry, len(obj.Geometries))
+		for i, g := range obj.Geometries {
+			var unit interface{}
+			var name string
+			switch layer {
+			case gomuni.TopologyRegions:
+				region := localizeRegion(data.country.GetRegionByID(g.ID), langs)
+				unit, name = region, region.Name
+			case gomuni.TopologyCities:
+				city := localizeCity(data.country.GetCityByID(g.ID), langs)
+				unit, name = city, city.Name
+			default:
+				town := localizeTown(data.country.GetTownByID(g.ID), langs)
+				unit, name = town, town.Name
+			}
+
+			if fields != "" {
+				g.Properties, _ = reduce(unit, fields).(map[string]interface{})
+			} else {
+				properties := make(map[string]interface{}, len(g.Properties))
+				for k, v := range g.Properties {
+					properties[k] = v
+				}
+				properties["name"] = name
+				g.Properties = properties
+			}
+			geometries[i] = g
+		}
+		reduced.Objects[layer] = gomuni.TopologyObject{Type: obj.Type, Geometries: geometries}
+	}
+	b, _ := json.Marshal(reduced)
+	w.Write(b)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+)
+
+var (
+	zipFile      = flag.String("zip", "", "zip archive of the ISTAT dataset")
+	regionFolder = flag.String("region", "", "folder of the regions shapefiles")
+	cityFolder   = flag.String("city", "", "folder of the cities shapefiles")
+	townFolder   = flag.String("town", "", "folder of the towns shapefiles")
+	output       = flag.String("o", "gomuni.snap.gz", "output file, compressed with gzip if it ends with .gz")
+)
+
+func main() {
+	flag.Parse()
+
+	opts := gomuni.Options{
+		RegionFolder: *regionFolder,
+		CityFolder:   *cityFolder,
+		TownFolder:   *townFolder,
+		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+	}
+
+	var country *gomuni.Country
+	var err error
+	if *zipFile != "" {
+		country, err = gomuni.LoadZip(*zipFile, opts)
+	} else {
+		country, err = gomuni.LoadWithOptions(opts)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := write(country, *output); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Snapshot written to", *output)
+}
+
+func write(country *gomuni.Country, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	var w io.Writer = bw
+	var zw *gzip.Writer
+	if strings.HasSuffix(filename, ".gz") {
+		zw, _ = gzip.NewWriterLevel(bw, gzip.BestCompression)
+		w = zw
+	}
+
+	err = country.WriteSnapshot(w)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+)
+
+//enrichColumns are the columns appended to each row
+var enrichColumns = []string{"region_id", "region_name", "province_id", "province_name", "town_id", "town_name"}
+
+var (
+	latNames = []string{"lat", "latitude", "latitudine"}
+	lngNames = []string{"lng", "lon", "long", "longitude", "longitudine"}
+)
+
+//enricher appends the units containing the point of each row
+type enricher struct {
+	country *gomuni.Country
+	format  string
+	lat     string
+	lng     string
+	header  bool
+	workers int
+}
+
+type stats struct {
+	rows       int
+	unresolved int
+}
+
+//row is a row of the input, with the output written by a worker.
+//The outputs are written in the order of the rows while they are resolved concurrently.
+type row struct {
+	n       int
+	fields  []string
+	line    []byte
+	out     []byte
+	problem string
+	done    chan struct{}
+}
+
+//enrich reads the rows from in and writes them with the enrichColumns to out.
+//The unresolved rows are written without the units and reported to unresolved, with their number from 1.
+func (e *enricher) enrich(in io.Reader, out io.Writer, unresolved func(n int, reason string)) (stats, error) {
+	var read func() (*row, error)
+	var resolve func(*row)
+
+	switch e.format {
+	case "csv", "tsv":
+		r := csv.NewReader(in)
+		r.FieldsPerRecord = -1
+		w := csv.NewWriter(out)
+		if e.format == "tsv" {
+			r.Comma, w.Comma = '\t', '\t'
+			r.LazyQuotes = true
+		}
+
+		latIndex, lngIndex, err := e.csvHeader(r, w)
+		if err != nil {
+			return stats{}, err
+		}
+
+		n := 0
+		read = func() (*row, error) {
+			fields, err := r.Read()
+			if err != nil {
+				return nil, err
+			}
+			n++
+			return &row{n: n, fields: fields}, nil
+		}
+		resolve = func(rw *row) {
+			var location gomuni.Location
+			location, rw.problem = e.resolveFields(rw.fields, latIndex, lngIndex)
+
+			var buf bytes.Buffer
+			cw := csv.NewWriter(&buf)
+			cw.Comma = w.Comma
+			cw.Write(append(rw.fields, locationColumns(location)...))
+			cw.Flush()
+			rw.out = buf.Bytes()
+		}
+
+	case "ndjson":
+		if e.lat == "" {
+			e.lat = "lat"
+		}
+		if e.lng == "" {
+			e.lng = "lng"
+		}
+
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		n := 0
+		read = func() (*row, error) {
+			for scanner.Scan() {
+				n++
+				if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+					return &row{n: n, line: append([]byte(nil), line...)}, nil
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		resolve = e.resolveLine
+
+	default:
+		return stats{}, fmt.Errorf("unknown format %q, expected csv, tsv or ndjson", e.format)
+	}
+
+	return e.run(read, resolve, out, unresolved)
+}
+
+//run resolves the rows with the workers and writes them in order
+func (e *enricher) run(read func() (*row, error), resolve func(*row), out io.Writer, unresolved func(int, string)) (stats, error) {
+	workers := e.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *row)
+	pending := make(chan *row, 64*workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for rw := range jobs {
+				resolve(rw)
+				close(rw.done)
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			rw, err := read()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			rw.done = make(chan struct{})
+			pending <- rw
+			jobs <- rw
+		}
+	}()
+
+	var s stats
+	var writeErr error
+	for rw := range pending {
+		<-rw.done
+		s.rows++
+		if rw.problem != "" {
+			s.unresolved++
+			unresolved(rw.n, rw.problem)
+		}
+		if writeErr == nil {
+			_, writeErr = out.Write(rw.out)
+		}
+	}
+
+	if readErr != nil {
+		return s, readErr
+	}
+	return s, writeErr
+}
+
+//csvHeader reads the header, if any, writes it with the enrichColumns and returns the indexes of the coordinates
+func (e *enricher) csvHeader(r *csv.Reader, w *csv.Writer) (int, int, error) {
+	var columns []string
+	if e.header {
+		var err error
+		if columns, err = r.Read(); err != nil {
+			return 0, 0, fmt.Errorf("cannot read the header: %v", err)
+		}
+		w.Write(append(append([]string(nil), columns...), enrichColumns...))
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	latIndex, err := columnIndex(columns, e.lat, latNames)
+	if err != nil {
+		return 0, 0, fmt.Errorf("latitude column: %v", err)
+	}
+	lngIndex, err := columnIndex(columns, e.lng, lngNames)
+	if err != nil {
+		return 0, 0, fmt.Errorf("longitude column: %v", err)
+	}
+	return latIndex, lngIndex, nil
+}
+
+//columnIndex returns the index of a column from its index, its name or one of the default names
+func columnIndex(columns []string, column string, defaults []string) (int, error) {
+	if i, err := strconv.Atoi(column); err == nil {
+		if i < 0 || (columns != nil && i >= len(columns)) {
+			return 0, fmt.Errorf("index %d out of the columns", i)
+		}
+		return i, nil
+	}
+	if columns == nil {
+		return 0, errors.New("without a header the column must be an index")
+	}
+
+	names := defaults
+	if column != "" {
+		names = []string{column}
+	}
+	for _, name := range names {
+		for i, c := range columns {
+			if strings.EqualFold(strings.TrimSpace(c), name) {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no column named %s", strings.Join(names, ", "))
+}
+
+//resolveFields resolves the point of a CSV row, returning the problem if it cannot be resolved
+func (e *enricher) resolveFields(fields []string, latIndex, lngIndex int) (gomuni.Location, string) {
+	if latIndex >= len(fields) || lngIndex >= len(fields) {
+		return gomuni.Location{}, "missing the coordinates"
+	}
+	lat, err := parseCoordinate(fields[latIndex])
+	if err != nil {
+		return gomuni.Location{}, "invalid latitude " + strconv.Quote(fields[latIndex])
+	}
+	lng, err := parseCoordinate(fields[lngIndex])
+	if err != nil {
+		return gomuni.Location{}, "invalid longitude " + strconv.Quote(fields[lngIndex])
+	}
+	return e.resolvePoint(gomuni.Point{Lat: lat, Lng: lng})
+}
+
+//resolveLine resolves the point of a NDJSON line, adding the enrichColumns to the object
+func (e *enricher) resolveLine(rw *row) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(rw.line, &object); err != nil {
+		rw.out, rw.problem = append(rw.line, '\n'), "invalid JSON object"
+		return
+	}
+
+	var location gomuni.Location
+	lat, latErr := parseJSONCoordinate(object[e.lat])
+	lng, lngErr := parseJSONCoordinate(object[e.lng])
+	switch {
+	case latErr != nil:
+		rw.problem = "invalid or missing " + e.lat
+	case lngErr != nil:
+		rw.problem = "invalid or missing " + e.lng
+	default:
+		location, rw.problem = e.resolvePoint(gomuni.Point{Lat: lat, Lng: lng})
+	}
+
+	// the fields are appended to the original line, to keep the order of its keys
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(rw.line, []byte("}")))
+	for i, value := range locationColumns(location) {
+		if len(object) > 0 || i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(enrichColumns[i])
+		v, _ := json.Marshal(value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteString("}\n")
+	rw.out = buf.Bytes()
+}
+
+func (e *enricher) resolvePoint(point gomuni.Point) (gomuni.Location, string) {
+	location := e.country.Resolve(point)
+	if location.Town == nil {
+		return location, fmt.Sprintf("no town at %v,%v", point.Lat, point.Lng)
+	}
+	return location, ""
+}
+
+//locationColumns returns the values of the enrichColumns, empty for the units not found
+func locationColumns(location gomuni.Location) []string {
+	values := make([]string, len(enrichColumns))
+	if location.Region != nil {
+		values[0], values[1] = location.Region.ID, location.Region.Name
+	}
+	if location.City != nil {
+		values[2], values[3] = location.City.ID, location.City.Name
+	}
+	if location.Town != nil {
+		values[4], values[5] = location.Town.ID, location.Town.Name
+	}
+	return values
+}
+
+//parseCoordinate parses a coordinate, also with the decimal comma
+func parseCoordinate(value string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+}
+
+//parseJSONCoordinate parses a coordinate given as a JSON number or string
+func parseJSONCoordinate(raw json.RawMessage) (float64, error) {
+	if len(raw) == 0 {
+		return 0, errors.New("missing")
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return parseCoordinate(s)
+	}
+	var f float64
+	err := json.Unmarshal(raw, &f)
+	return f, err
+}
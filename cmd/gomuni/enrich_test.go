@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/enrichman/gomuni"
+	"github.com/enrichman/gomuni/internal/fixture"
+)
+
+func newTestEnricher(format string) *enricher {
+	country, err := gomuni.LoadFS(fixture.Italy(), gomuni.Options{})
+	if err != nil {
+		panic(err)
+	}
+	return &enricher{country: country, format: format, header: true, workers: 4}
+}
+
+func Test_enrich(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		lat, lng   string
+		header     bool
+		input      string
+		want       string
+		unresolved []int
+	}{
+		{
+			name:   "csv",
+			format: "csv", header: true,
+			input: "id,Latitude,lng\n1,45,7.5\n2,\"44,5\",11\n3,0,0\n",
+			want: "id,Latitude,lng,region_id,region_name,province_id,province_name,town_id,town_name\n" +
+				"1,45,7.5,1,Piemonte,1,Torino,001272,Torino\n" +
+				"2,\"44,5\",11,8,Emilia-Romagna,99,Rimini,099014,Rimini\n" +
+				"3,0,0,,,,,,\n",
+			unresolved: []int{3},
+		},
+		{
+			name:   "tsv by index",
+			format: "tsv", lat: "1", lng: "0",
+			input:      "8.5\t45\nx\t45\n",
+			want:       "8.5\t45\t1\tPiemonte\t1\tTorino\t001156\tMoncalieri\nx\t45\t\t\t\t\t\t\n",
+			unresolved: []int{2},
+		},
+		{
+			name:   "ndjson",
+			format: "ndjson",
+			input:  "{\"id\":1,\"lat\":45,\"lng\":7.5}\n\n{\"lat\":\"44.5\",\"lng\":11}\n{}\n",
+			want: `{"id":1,"lat":45,"lng":7.5,"region_id":"1","region_name":"Piemonte","province_id":"1","province_name":"Torino","town_id":"001272","town_name":"Torino"}` + "\n" +
+				`{"lat":"44.5","lng":11,"region_id":"8","region_name":"Emilia-Romagna","province_id":"99","province_name":"Rimini","town_id":"099014","town_name":"Rimini"}` + "\n" +
+				`{"region_id":"","region_name":"","province_id":"","province_name":"","town_id":"","town_name":""}` + "\n",
+			unresolved: []int{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnricher(tt.format)
+			e.lat, e.lng, e.header = tt.lat, tt.lng, tt.header || tt.format == "csv"
+
+			var out bytes.Buffer
+			unresolved := make([]int, 0)
+			s, err := e.enrich(strings.NewReader(tt.input), &out, func(n int, reason string) {
+				unresolved = append(unresolved, n)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("enrich() output\n%s\nwant\n%s", out.String(), tt.want)
+			}
+			if s.unresolved != len(tt.unresolved) || len(unresolved) != len(tt.unresolved) {
+				t.Fatalf("enrich() unresolved rows %v, want %v", unresolved, tt.unresolved)
+			}
+			for i := range unresolved {
+				if unresolved[i] != tt.unresolved[i] {
+					t.Errorf("enrich() unresolved rows %v, want %v", unresolved, tt.unresolved)
+				}
+			}
+		})
+	}
+}
+
+func Test_enrichErrors(t *testing.T) {
+	e := newTestEnricher("csv")
+	if _, err := e.enrich(strings.NewReader("a,b\n1,2\n"), &bytes.Buffer{}, func(int, string) {}); err == nil {
+		t.Error("enrich() without the coordinates columns should fail")
+	}
+
+	e = newTestEnricher("xml")
+	if _, err := e.enrich(strings.NewReader(""), &bytes.Buffer{}, func(int, string) {}); err == nil {
+		t.Error("enrich() of an unknown format should fail")
+	}
+}
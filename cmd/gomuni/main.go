@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/enrichman/gomuni"
+)
+
+var (
+	zipFile      = flag.String("zip", "", "zip archive of the ISTAT dataset")
+	snapshotFile = flag.String("snapshot", "", "snapshot of the dataset, gzipped if it ends with .gz")
+	regionFolder = flag.String("region", "", "folder of the regions shapefiles")
+	cityFolder   = flag.String("city", "", "folder of the cities shapefiles")
+	townFolder   = flag.String("town", "", "folder of the towns shapefiles")
+
+	format  = flag.String("format", "", "input format: csv, tsv or ndjson (default from the file extension, or csv)")
+	latCol  = flag.String("lat", "", "name or index (from 0) of the latitude column (default lat, latitude or latitudine)")
+	lngCol  = flag.String("lng", "", "name or index (from 0) of the longitude column (default lng, lon, longitude or longitudine)")
+	header  = flag.Bool("header", true, "the CSV and TSV input starts with a header")
+	workers = flag.Int("workers", runtime.NumCPU(), "number of rows resolved concurrently")
+	quiet   = flag.Bool("q", false, "do not report the unresolved rows, only their count")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: gomuni [flags] [file]
+
+Appends the region, province and municipality of each row of a CSV, TSV or NDJSON file,
+or of the standard input, and writes the rows to the standard output.
+Without a dataset flag the dataset embedded with the gomuni_embed tag is used.
+
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("gomuni: ")
+
+	in := io.Reader(os.Stdin)
+	name := flag.Arg(0)
+	if name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	e := &enricher{format: *format, lat: *latCol, lng: *lngCol, header: *header, workers: *workers}
+	if e.format == "" {
+		e.format = formatOf(name)
+	}
+
+	country, err := loadCountry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	e.country = country
+
+	out := bufio.NewWriter(os.Stdout)
+	stats, err := e.enrich(in, out, func(row int, reason string) {
+		if !*quiet {
+			log.Printf("row %d: %s", row, reason)
+		}
+	})
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%d rows, %d unresolved", stats.rows, stats.unresolved)
+	if stats.unresolved > 0 {
+		os.Exit(2)
+	}
+}
+
+//formatOf returns the format of a file from its extension
+func formatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return "csv"
+}
+
+func loadCountry() (*gomuni.Country, error) {
+	opts := gomuni.Options{
+		RegionFolder: *regionFolder,
+		CityFolder:   *cityFolder,
+		TownFolder:   *townFolder,
+	}
+
+	switch {
+	case *snapshotFile != "":
+		f, err := os.Open(*snapshotFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		var r io.Reader = bufio.NewReader(f)
+		if strings.HasSuffix(*snapshotFile, ".gz") {
+			if r, err = gzip.NewReader(r); err != nil {
+				return nil, err
+			}
+		}
+		return gomuni.LoadSnapshot(r)
+	case *zipFile != "":
+		return gomuni.LoadZip(*zipFile, opts)
+	case opts.RegionFolder != "" || opts.CityFolder != "" || opts.TownFolder != "":
+		return gomuni.LoadWithOptions(opts)
+	}
+	return gomuni.Default()
+}
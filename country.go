@@ -1,15 +1,7 @@
 package gomuni
 
 import (
-	"io/ioutil"
-	"log"
 	"strings"
-
-	"errors"
-
-	"github.com/dhconnelly/rtreego"
-	shp "github.com/jonas-p/go-shp"
-	geo "github.com/kellydunn/golang-geo"
 )
 
 //Point represent a geolocation point with latitude and longitude
@@ -22,8 +14,13 @@ type Point struct {
 type Country struct {
 	Regions []*Region `json:"regions,omitempty"`
 
-	regionsTree *rtreego.Rtree
-	regionsMap  map[string]*Region
+	regionsIndex spatialIndex
+	regionsMap   map[string]*Region
+
+	citiesMap         map[string]*City
+	citiesByShortname map[string]*City
+	townsMap          map[string]*Town
+	names             nameIndex
 }
 
 //RegionsGetter can be used to retrive a region from its ID or from a geolocation point
@@ -32,28 +29,34 @@ type RegionsGetter interface {
 	GetRegionsByPoint(lat, lng float32) []*Region
 }
 
-//Load all the country with the Regions, Cities and Towns
-func Load(regionFolder, cityFolder, townFolder string) *Country {
-	country := loadCountryWithRegions(regionFolder)
-	country.loadRegionsWithCities(cityFolder)
-	country.loadCitiesWithTowns(townFolder)
-	return country
-}
-
 //GetRegionByID returns the Region with the provided ID
 func (c *Country) GetRegionByID(ID string) *Region {
 	return c.regionsMap[ID]
 }
 
+//GetCityByID returns the City with the provided ISTAT province code, with or without the leading zeros
+func (c *Country) GetCityByID(ID string) *City {
+	if city, ok := c.citiesMap[ID]; ok {
+		return city
+	}
+	return c.citiesMap[strings.TrimLeft(ID, "0")]
+}
+
+//GetCityByShortname returns the City with the provided sigla, like TO or RM, ignoring the case
+func (c *Country) GetCityByShortname(shortname string) *City {
+	return c.citiesByShortname[strings.ToUpper(shortname)]
+}
+
+//GetTownByID returns the Town with the provided ISTAT municipality code, with or without the leading zeros
+func (c *Country) GetTownByID(ID string) *Town {
+	return c.townsMap[buildIstatID(ID)]
+}
+
 //GetRegionsByPoint returns the Regions having their bounding box over the provided geolocation point
 func (c *Country) GetRegionsByPoint(point Point) []*Region {
-	location := rtreego.Point{point.Lat, point.Lng}
-	results := c.regionsTree.SearchIntersect(location.ToRect(0.01))
-
 	regions := make([]*Region, 0)
-	for _, s := range results {
-		r := s.(*Region)
-		regions = append(regions, r)
+	for _, i := range c.regionsIndex.search(pointBox(point, 0.01)) {
+		regions = append(regions, c.Regions[i])
 	}
 
 	return regions
@@ -87,209 +90,82 @@ func (c *Country) FindTownByPoint(point Point) *Town {
 	return nil
 }
 
-func loadCountryWithRegions(folder string) *Country {
-	regions := make([]*Region, 0)
-	regionsTree := rtreego.NewTree(2, 25, 50)
-	regionsMap := make(map[string]*Region)
-
-	loaded := false
-	files, _ := ioutil.ReadDir(folder)
-	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".shp") {
-			reader, err := shp.Open(folder + "/" + f.Name())
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer reader.Close()
-
-			for reader.Next() {
-				n, s := reader.Shape()
-				switch s.(type) {
-				case *shp.Polygon:
-					codReg := reader.ReadAttribute(n, 0)
-					nameReg := reader.ReadAttribute(n, 1)
-
-					reg := &Region{
-						ID:         codReg,
-						Name:       nameReg,
-						Cities:     make([]*City, 0),
-						citiesTree: rtreego.NewTree(2, 25, 50),
-						citiesMap:  make(map[string]*City),
-					}
-
-					p := s.(*shp.Polygon)
-
-					// load bounding box
-					minPoint, _ := toLatLon(p.Box.MinX, p.Box.MinY, 32, "N")
-					maxPoint, _ := toLatLon(p.Box.MaxX, p.Box.MaxY, 32, "N")
-					reg.BBox = shp.Box{
-						MinX: minPoint.Lat,
-						MinY: minPoint.Lng,
-						MaxX: maxPoint.Lat,
-						MaxY: maxPoint.Lng,
-					}
-
-					// load polygon
-					points := make([]*geo.Point, 0)
-					for _, point := range p.Points {
-						point, _ := toLatLon(point.X, point.Y, 32, "N")
-						points = append(points, geo.NewPoint(point.Lat, point.Lng))
-					}
-					reg.polygon = geo.NewPolygon(points)
-
-					regions = append(regions, reg)
-					regionsMap[reg.ID] = reg
-					regionsTree.Insert(reg)
-				}
-			}
-
-			loaded = true
+//FindRegionByPoint returns the Region containing the Point, or nil
+func (c *Country) FindRegionByPoint(point Point) *Region {
+	for _, r := range c.GetRegionsByPoint(point) {
+		if r.Contains(point) {
+			return r
 		}
 	}
-
-	if !loaded {
-		err := errors.New("Regions not loaded!")
-		panic(err)
-	}
-
-	return &Country{regions, regionsTree, regionsMap}
+	return nil
 }
 
-func (c *Country) loadRegionsWithCities(folder string) {
-	files, _ := ioutil.ReadDir(folder)
-
-	loaded := false
-	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".shp") {
-			reader, err := shp.Open(folder + "/" + f.Name())
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer reader.Close()
-
-			for reader.Next() {
-				n, s := reader.Shape()
-				switch s.(type) {
-				case *shp.Polygon:
-					regID := reader.ReadAttribute(n, 0)
-					cityID := reader.ReadAttribute(n, 2)
-					cityName := reader.ReadAttribute(n, 3)
-					cityShortname := reader.ReadAttribute(n, 5)
-					maincityFlag := reader.ReadAttribute(n, 6)
-
-					city := &City{
-						RegionID:  regID,
-						ID:        cityID,
-						Name:      cityName,
-						Shortname: cityShortname,
-						Maincity:  (maincityFlag == "1"),
-						Towns:     make([]*Town, 0),
-						townsTree: rtreego.NewTree(2, 25, 350),
-						townsMap:  make(map[string]*Town),
-					}
-
-					p := s.(*shp.Polygon)
-
-					// load bounding box
-					minPoint, _ := toLatLon(p.Box.MinX, p.Box.MinY, 32, "N")
-					maxPoint, _ := toLatLon(p.Box.MaxX, p.Box.MaxY, 32, "N")
-					city.BBox = shp.Box{
-						MinX: minPoint.Lat,
-						MinY: minPoint.Lng,
-						MaxX: maxPoint.Lat,
-						MaxY: maxPoint.Lng,
-					}
-
-					// load polygon
-					points := make([]*geo.Point, 0)
-					for _, point := range p.Points {
-						point, _ := toLatLon(point.X, point.Y, 32, "N")
-						points = append(points, geo.NewPoint(point.Lat, point.Lng))
-					}
-					city.polygon = geo.NewPolygon(points)
-
-					region := c.GetRegionByID(regID)
-					region.addCity(city)
-				}
+//FindCityByPoint returns the City containing the Point, or nil
+func (c *Country) FindCityByPoint(point Point) *City {
+	for _, r := range c.GetRegionsByPoint(point) {
+		for _, city := range r.GetCitiesByPoint(point) {
+			if city.Contains(point) {
+				return city
 			}
-
-			loaded = true
 		}
 	}
-
-	if !loaded {
-		err := errors.New("Cities not loaded!")
-		panic(err)
-	}
+	return nil
 }
 
-func (c *Country) loadCitiesWithTowns(folder string) {
-	files, _ := ioutil.ReadDir(folder)
-
-	loaded := false
-	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".shp") {
-			reader, err := shp.Open(folder + "/" + f.Name())
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer reader.Close()
-
-			for reader.Next() {
-				n, s := reader.Shape()
-				switch s.(type) {
-				case *shp.Polygon:
-					regID := reader.ReadAttribute(n, 0)
-					cityID := reader.ReadAttribute(n, 2)
-					townID := reader.ReadAttribute(n, 3)
-					townName := reader.ReadAttribute(n, 4)
-
-					town := &Town{
-						ID:       buildIstatID(townID),
-						RegionID: regID,
-						CityID:   cityID,
-						Name:     townName,
-					}
-
-					p := s.(*shp.Polygon)
+//Location is the Region, City and Town of a Point. The units not found are nil.
+type Location struct {
+	Region *Region
+	City   *City
+	Town   *Town
+}
 
-					// load bounding box
-					minPoint, _ := toLatLon(p.Box.MinX, p.Box.MinY, 32, "N")
-					maxPoint, _ := toLatLon(p.Box.MaxX, p.Box.MaxY, 32, "N")
-					town.BBox = shp.Box{
-						MinX: minPoint.Lat,
-						MinY: minPoint.Lng,
-						MaxX: maxPoint.Lat,
-						MaxY: maxPoint.Lng,
-					}
+//Resolve returns the Region, the City and the Town containing the Point.
+//The parents of the Town are taken by ID, so that the chain is always consistent
+//also along the borders, where the polygons of the different levels can slightly differ.
+func (c *Country) Resolve(point Point) Location {
+	town := c.FindTownByPoint(point)
+	if town == nil {
+		return Location{Region: c.FindRegionByPoint(point), City: c.FindCityByPoint(point)}
+	}
 
-					// load polygon
-					points := make([]*geo.Point, 0)
-					for _, point := range p.Points {
-						point, _ := toLatLon(point.X, point.Y, 32, "N")
-						points = append(points, geo.NewPoint(point.Lat, point.Lng))
-					}
-					town.polygon = geo.NewPolygon(points)
+	location := Location{Region: c.GetRegionByID(town.RegionID), Town: town}
+	if location.Region != nil {
+		location.City = location.Region.GetCityByID(town.CityID)
+	}
+	return location
+}
 
-					region := c.GetRegionByID(regID)
-					city := region.GetCityByID(cityID)
-					city.addTown(town)
-				}
-			}
+func newCountry() *Country {
+	return &Country{
+		Regions:    make([]*Region, 0),
+		regionsMap: make(map[string]*Region),
 
-			loaded = true
-		}
+		citiesMap:         make(map[string]*City),
+		citiesByShortname: make(map[string]*City),
+		townsMap:          make(map[string]*Town),
 	}
+}
 
-	if !loaded {
-		err := errors.New("Towns not loaded!")
-		panic(err)
-	}
+func (c *Country) addRegion(region *Region) {
+	c.Regions = append(c.Regions, region)
+	c.regionsMap[region.ID] = region
 }
 
-func buildIstatID(id string) string {
-	for len(id) < 6 {
-		id = "0" + id
+//buildIndexes indexes all the Cities and Towns of the Country by their codes, and all the units by name
+func (c *Country) buildIndexes() {
+	c.names = nameIndex{}
+	for _, r := range c.Regions {
+		c.names.addAll(r.Name, r.Names, levelRegion, Location{Region: r})
+		for _, city := range r.Cities {
+			c.names.addAll(city.Name, city.Names, levelCity, Location{Region: r, City: city})
+			c.citiesMap[city.ID] = city
+			if city.Shortname != "" {
+				c.citiesByShortname[strings.ToUpper(city.Shortname)] = city
+			}
+			for _, town := range city.Towns {
+				c.townsMap[town.ID] = town
+				c.names.addAll(town.Name, town.Names, levelTown, Location{Region: r, City: city, Town: town})
+			}
+		}
 	}
-	return id
 }
@@ -14,6 +14,14 @@ func initCountry() *Country {
 	_ = godotenv.Load()
 	rand.Seed(time.Now().Unix())
 
+	if zipFile := os.Getenv("DATASET_ZIP"); zipFile != "" {
+		country, err := LoadZip(zipFile, Options{})
+		if err != nil {
+			panic(err)
+		}
+		return country
+	}
+
 	regionFolder := os.Getenv("REGION_FOLDER")
 	cityFolder := os.Getenv("CITY_FOLDER")
 	townFolder := os.Getenv("TOWN_FOLDER")
@@ -0,0 +1,93 @@
+package gomuni
+
+import (
+	"testing"
+
+	"github.com/enrichman/gomuni/internal/fixture"
+)
+
+//newTestCountry loads the Country of the fixture dataset:
+//Piemonte (01) with the city of Torino (001) split in the towns of Torino (001272) and Moncalieri (001156),
+//and Emilia-Romagna (08) with the city and town of Rimini (099, 099014) surrounding San Marino.
+func newTestCountry() *Country {
+	country, err := LoadFS(fixture.Italy(), Options{})
+	if err != nil {
+		panic(err)
+	}
+	return country
+}
+
+func Test_Resolve(t *testing.T) {
+	country := newTestCountry()
+
+	tests := []struct {
+		name               string
+		point              Point
+		region, city, town string
+	}{
+		{"Torino", Point{Lat: 45, Lng: 7.5}, "1", "1", "001272"},
+		{"Moncalieri", Point{Lat: 45, Lng: 8.5}, "1", "1", "001156"},
+		{"Rimini", Point{Lat: 44.5, Lng: 11}, "8", "99", "099014"},
+		{"San Marino", Point{Lat: 45, Lng: 11}, "", "", ""},
+		{"sea", Point{Lat: 40, Lng: 12}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := country.Resolve(tt.point)
+			if id := regionID(location.Region); id != tt.region {
+				t.Errorf("Resolve().Region = %q, want %q", id, tt.region)
+			}
+			if id := cityID(location.City); id != tt.city {
+				t.Errorf("Resolve().City = %q, want %q", id, tt.city)
+			}
+			if id := townID(location.Town); id != tt.town {
+				t.Errorf("Resolve().Town = %q, want %q", id, tt.town)
+			}
+
+			if id := regionID(country.FindRegionByPoint(tt.point)); id != tt.region {
+				t.Errorf("FindRegionByPoint() = %q, want %q", id, tt.region)
+			}
+			if id := cityID(country.FindCityByPoint(tt.point)); id != tt.city {
+				t.Errorf("FindCityByPoint() = %q, want %q", id, tt.city)
+			}
+		})
+	}
+}
+
+func regionID(r *Region) string {
+	if r == nil {
+		return ""
+	}
+	return r.ID
+}
+
+func cityID(c *City) string {
+	if c == nil {
+		return ""
+	}
+	return c.ID
+}
+
+func townID(t *Town) string {
+	if t == nil {
+		return ""
+	}
+	return t.ID
+}
+
+func Test_CountryIndexes(t *testing.T) {
+	country := newTestCountry()
+
+	if town := country.GetTownByID("1156"); townID(town) != "001156" {
+		t.Errorf("GetTownByID(1156) = %q, want 001156", townID(town))
+	}
+	if town := country.GetTownByID("099999"); town != nil {
+		t.Errorf("GetTownByID(099999) = %q, want nil", townID(town))
+	}
+	if city := country.GetCityByID("099"); cityID(city) != "99" {
+		t.Errorf("GetCityByID(099) = %q, want 99", cityID(city))
+	}
+	if city := country.GetCityByShortname("rn"); cityID(city) != "99" {
+		t.Errorf("GetCityByShortname(rn) = %q, want 99", cityID(city))
+	}
+}
@@ -0,0 +1,49 @@
+package gomuni
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//newCSVReader returns a reader of a CSV file, like the ones published by ISTAT:
+//the content is converted from Latin-1 if needed, without the BOM, and the fields are
+//separated by semicolons or commas, whichever is more used in the header.
+func newCSVReader(r io.Reader) (*csv.Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		data = []byte(decodeText(data))
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	return reader, nil
+}
+
+//normalizeHeader lowercases the name of a column and removes the spaces and the punctuation,
+//so that "Codice Comune" and "codice_comune" are the same
+func normalizeHeader(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package gomuni
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sync"
+)
+
+//go:generate go run ./cmd/gomuni-snapshot -zip shp-files/Limiti_2016_WGS84.zip -o dataset/gomuni.snap.gz
+
+var defaultCountry struct {
+	once    sync.Once
+	country *Country
+	err     error
+}
+
+//Default returns the Country of the dataset embedded in the binary.
+//The dataset is embedded building with the gomuni_embed tag, after generating it with go generate.
+//It is decompressed and loaded only once, the following calls return the same Country.
+func Default() (*Country, error) {
+	defaultCountry.once.Do(func() {
+		if len(embeddedDataset) == 0 {
+			defaultCountry.err = ErrNoEmbeddedDataset
+			return
+		}
+
+		r, err := gzip.NewReader(bytes.NewReader(embeddedDataset))
+		if err != nil {
+			defaultCountry.err = err
+			return
+		}
+		defer r.Close()
+
+		defaultCountry.country, defaultCountry.err = LoadSnapshot(r)
+	})
+
+	return defaultCountry.country, defaultCountry.err
+}
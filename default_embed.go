@@ -0,0 +1,11 @@
+//go:build gomuni_embed
+// +build gomuni_embed
+
+package gomuni
+
+import _ "embed"
+
+//embeddedDataset is the gzipped snapshot generated with go generate
+//
+//go:embed dataset/gomuni.snap.gz
+var embeddedDataset []byte
@@ -0,0 +1,6 @@
+//go:build !gomuni_embed
+// +build !gomuni_embed
+
+package gomuni
+
+var embeddedDataset []byte
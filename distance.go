@@ -0,0 +1,193 @@
+package gomuni
+
+import (
+	"math"
+	"sort"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//earthRadius is the mean radius of the Earth in kilometers
+const earthRadius = 6371.0088
+
+//Distance returns the great-circle distance in kilometers between two Points
+func Distance(a, b Point) float64 {
+	dLat, dLng := rad(b.Lat-a.Lat), rad(b.Lng-a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+//distanceTo returns the distance in kilometers from the Point to the nearest edge of the Ring.
+//The edges are projected on the plane tangent to the Earth in the Point, that is accurate
+//for the distances of some hundreds of kilometers used to look for the nearby units.
+func (r Ring) distanceTo(point Point) float64 {
+	kmLat := rad(earthRadius)
+	kmLng := kmLat * math.Cos(rad(point.Lat))
+	project := func(p Point) (float64, float64) {
+		return (p.Lng - point.Lng) * kmLng, (p.Lat - point.Lat) * kmLat
+	}
+
+	shortest := math.Inf(1)
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		ax, ay := project(r[j])
+		bx, by := project(r[i])
+		if d := segmentDistance(ax, ay, bx, by); d < shortest {
+			shortest = d
+		}
+	}
+	return shortest
+}
+
+//segmentDistance returns the distance of the origin from the segment between a and b
+func segmentDistance(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+//distanceTo returns the distance in kilometers from the Point to the MultiPolygon, 0 if it contains the Point
+func (m MultiPolygon) distanceTo(point Point) float64 {
+	if m.Contains(point) {
+		return 0
+	}
+
+	shortest := math.Inf(1)
+	for _, p := range m {
+		for _, ring := range p {
+			if d := ring.distanceTo(point); d < shortest {
+				shortest = d
+			}
+		}
+	}
+	return shortest
+}
+
+//radiusBox returns the box around the Point containing the circle with the radius in kilometers,
+//with the latitudes on the X axis as in the bounding boxes of the units
+func radiusBox(point Point, km float64) shp.Box {
+	dLat := deg(km / earthRadius)
+	dLng := 180.0
+	if cos := math.Cos(rad(point.Lat)); cos > 1e-9 {
+		dLng = math.Min(180, dLat/cos)
+	}
+
+	return shp.Box{MinX: point.Lat - dLat, MinY: point.Lng - dLng, MaxX: point.Lat + dLat, MaxY: point.Lng + dLng}
+}
+
+//townsInBox returns the Towns having their bounding box intersecting the box
+func (c *Country) townsInBox(box shp.Box) []*Town {
+	towns := make([]*Town, 0)
+	for _, i := range c.regionsIndex.search(box) {
+		r := c.Regions[i]
+		for _, j := range r.citiesIndex.search(box) {
+			city := r.Cities[j]
+			for _, k := range city.townsIndex.search(box) {
+				towns = append(towns, city.Towns[k])
+			}
+		}
+	}
+	return towns
+}
+
+//NearestTown is the Town found by FindNearestTown, with its distance in kilometers from the Point.
+//Approximate is true when the Town does not contain the Point.
+type NearestTown struct {
+	Town        *Town
+	Distance    float64
+	Approximate bool
+}
+
+//FindNearestTown returns the Town containing the Point, like FindTownByPoint.
+//If no Town contains it, like for a point just offshore or in a gap between the polygons,
+//it returns the Town with the nearest boundary within maxDistance kilometers, flagged as approximate.
+//The Town is nil if there is no Town within maxDistance.
+func (c *Country) FindNearestTown(point Point, maxDistance float64) NearestTown {
+	if town := c.FindTownByPoint(point); town != nil {
+		return NearestTown{Town: town}
+	}
+	if maxDistance <= 0 {
+		return NearestTown{}
+	}
+
+	nearest := NearestTown{Distance: math.Inf(1), Approximate: true}
+	for _, t := range c.townsInBox(radiusBox(point, maxDistance)) {
+		if d := t.polygon.distanceTo(point); d <= maxDistance && d < nearest.Distance {
+			nearest.Town, nearest.Distance = t, d
+		}
+	}
+
+	if nearest.Town == nil {
+		return NearestTown{}
+	}
+	return nearest
+}
+
+//DistanceMode selects how the distance of a Town from a Point is measured
+type DistanceMode int
+
+const (
+	//PolygonDistance is the distance from the nearest point of the boundary, 0 for the Towns containing the Point
+	PolygonDistance DistanceMode = iota
+	//CentroidDistance is the distance from the centroid of the Town
+	CentroidDistance
+)
+
+//TownDistance is a Town with its distance in kilometers from a Point
+type TownDistance struct {
+	Town     *Town
+	Distance float64
+}
+
+//distance returns the distance in kilometers of the Town from the Point
+func (mode DistanceMode) distance(t *Town, point Point) float64 {
+	if mode == CentroidDistance {
+		return Distance(point, t.Centroid())
+	}
+	return t.polygon.distanceTo(point)
+}
+
+//TownsWithinRadius returns the Towns within km kilometers from the Point, sorted by distance
+func (c *Country) TownsWithinRadius(point Point, km float64, mode DistanceMode) []TownDistance {
+	towns := make([]TownDistance, 0)
+	for _, t := range c.townsInBox(radiusBox(point, km)) {
+		if d := mode.distance(t, point); d <= km {
+			towns = append(towns, TownDistance{Town: t, Distance: d})
+		}
+	}
+
+	sortByDistance(towns)
+	return towns
+}
+
+//maxRadius is the radius in kilometers covering the whole Country from any point
+const maxRadius = 2500
+
+//NearestTowns returns the k Towns nearest to the Point, sorted by distance.
+//The search starts from the surroundings of the Point and it is widened until k Towns are found.
+func (c *Country) NearestTowns(point Point, k int, mode DistanceMode) []TownDistance {
+	if k <= 0 {
+		return []TownDistance{}
+	}
+
+	for km := 10.0; ; km *= 2 {
+		towns := c.TownsWithinRadius(point, km, mode)
+		if len(towns) >= k {
+			return towns[:k]
+		}
+		if km >= maxRadius {
+			return towns
+		}
+	}
+}
+
+func sortByDistance(towns []TownDistance) {
+	sort.SliceStable(towns, func(i, j int) bool {
+		if towns[i].Distance != towns[j].Distance {
+			return towns[i].Distance < towns[j].Distance
+		}
+		return towns[i].Town.ID < towns[j].Town.ID
+	})
+}
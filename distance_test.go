@@ -0,0 +1,92 @@
+package gomuni
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_Distance(t *testing.T) {
+	// Torino - Milano, about 126 km
+	if d := Distance(Point{Lat: 45.0703, Lng: 7.6869}, Point{Lat: 45.4642, Lng: 9.19}); math.Abs(d-125.8) > 1 {
+		t.Errorf("Distance(Torino, Milano) = %v, want about 125.8", d)
+	}
+}
+
+func Test_FindNearestTown(t *testing.T) {
+	country := newTestCountry()
+
+	// 0.1 degrees of longitude at 45N are about 7.86 km
+	tests := []struct {
+		name        string
+		point       Point
+		maxDistance float64
+		town        string
+		distance    float64
+		approximate bool
+	}{
+		{"inside", Point{Lat: 45, Lng: 7.5}, 10, "001272", 0, false},
+		{"west of Torino", Point{Lat: 45, Lng: 6.9}, 10, "001272", 7.86, true},
+		{"too far", Point{Lat: 45, Lng: 6.9}, 5, "", 0, false},
+		{"in San Marino", Point{Lat: 45, Lng: 11}, 10, "099014", 7.86, true},
+		{"no max distance", Point{Lat: 45, Lng: 6.9}, 0, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := country.FindNearestTown(tt.point, tt.maxDistance)
+			if townID(got.Town) != tt.town || got.Approximate != tt.approximate || math.Abs(got.Distance-tt.distance) > 0.05 {
+				t.Errorf("FindNearestTown() = %s %v %v, want %s %v %v", townID(got.Town), got.Distance, got.Approximate, tt.town, tt.distance, tt.approximate)
+			}
+		})
+	}
+}
+
+func Test_TownsWithinRadius(t *testing.T) {
+	country := newTestCountry()
+
+	// 4 km from Moncalieri, the centroids of Torino and Moncalieri are 35 and 43 km away
+	point := Point{Lat: 45, Lng: 7.95}
+
+	tests := []struct {
+		name string
+		km   float64
+		mode DistanceMode
+		want []string
+	}{
+		{"polygon, inside Torino", 5, PolygonDistance, []string{"001272", "001156"}},
+		{"polygon, only Torino", 1, PolygonDistance, []string{"001272"}},
+		{"centroid", 50, CentroidDistance, []string{"001272", "001156"}},
+		{"centroid, only Torino", 40, CentroidDistance, []string{"001272"}},
+		{"centroid, too close", 10, CentroidDistance, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := townIDs(country.TownsWithinRadius(point, tt.km, tt.mode))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("TownsWithinRadius(%v) = %v, want %v", tt.km, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NearestTowns(t *testing.T) {
+	country := newTestCountry()
+
+	got := country.NearestTowns(Point{Lat: 45, Lng: 7.95}, 2, PolygonDistance)
+	if ids := townIDs(got); strings.Join(ids, ",") != "001272,001156" || got[0].Distance != 0 {
+		t.Errorf("NearestTowns(2) = %v, want Torino then Moncalieri", got)
+	}
+
+	// all the towns are returned when k is more than them
+	if got := country.NearestTowns(Point{Lat: 45, Lng: 7.95}, 10, CentroidDistance); len(got) != 3 || got[2].Town.ID != "099014" {
+		t.Errorf("NearestTowns(10) = %v, want the 3 towns ending with Rimini", townIDs(got))
+	}
+}
+
+func townIDs(towns []TownDistance) []string {
+	ids := make([]string, len(towns))
+	for i, t := range towns {
+		ids[i] = t.Town.ID
+	}
+	return ids
+}
@@ -0,0 +1,61 @@
+package gomuni
+
+import (
+	"errors"
+	"fmt"
+)
+
+//ErrNoShapefiles is returned when a layer folder does not contain any .shp file
+var ErrNoShapefiles = errors.New("gomuni: no shapefiles found")
+
+//ErrUnsupportedCRS is returned when the coordinate reference system of a shapefile cannot be converted to WGS84
+var ErrUnsupportedCRS = errors.New("gomuni: unsupported coordinate reference system")
+
+//ErrUnknownSchema is returned when the DBF fields of a layer do not match any known Vintage
+var ErrUnknownSchema = errors.New("gomuni: unknown schema")
+
+//ErrInvalidSnapshot is returned when a snapshot is corrupted or written with another format version
+var ErrInvalidSnapshot = errors.New("gomuni: invalid snapshot")
+
+//ErrNoEmbeddedDataset is returned by Default when the binary was not built with the gomuni_embed tag
+var ErrNoEmbeddedDataset = errors.New("gomuni: no embedded dataset, build with the gomuni_embed tag")
+
+//OrphanError is returned when a City or a Town references a parent that was not loaded
+type OrphanError struct {
+	Level    string
+	ID       string
+	ParentID string
+}
+
+func (e *OrphanError) Error() string {
+	return fmt.Sprintf("gomuni: %s %s references unknown parent %s", e.Level, e.ID, e.ParentID)
+}
+
+//ShapefileError is returned when a shapefile cannot be read.
+//Record is the index of the offending record, or -1 when the error is not related to a single record.
+type ShapefileError struct {
+	Path   string
+	Record int
+	Err    error
+}
+
+func (e *ShapefileError) Error() string {
+	if e.Record < 0 {
+		return fmt.Sprintf("gomuni: %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("gomuni: %s: record %d: %v", e.Path, e.Record, e.Err)
+}
+
+//Unwrap returns the underlying error
+func (e *ShapefileError) Unwrap() error {
+	return e.Err
+}
+
+//ErrInvalidSuccessions is returned when the CSV of the administrative changes cannot be read
+var ErrInvalidSuccessions = errors.New("gomuni: invalid successions")
+
+//ErrInvalidNames is returned when the CSV of the localized names cannot be read
+var ErrInvalidNames = errors.New("gomuni: invalid names")
+
+//ErrInvalidGeoJSON is returned when a GeoJSON geometry cannot be read
+var ErrInvalidGeoJSON = errors.New("gomuni: invalid GeoJSON")
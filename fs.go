@@ -0,0 +1,127 @@
+package gomuni
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+//layerPrefixes are the prefixes of the ISTAT layer names, like Reg2016_WGS84 or ProvCM01012017_WGS84
+var layerPrefixes = map[string][]string{
+	levelRegion: {"reg"},
+	levelCity:   {"provcm", "cmprov", "prov"},
+	levelTown:   {"com"},
+}
+
+//LoadFS loads all the country with the Regions, Cities and Towns from a file system,
+//like an opened zip archive, an embedded directory or an in-memory fixture.
+//The folders of the Options are directories of fsys. If empty, the directories are found
+//by the name of the ISTAT layers (Reg, ProvCM or CMProv, Com).
+func LoadFS(fsys fs.FS, opts Options) (*Country, error) {
+	folders := map[string]string{levelRegion: opts.RegionFolder, levelCity: opts.CityFolder, levelTown: opts.TownFolder}
+
+	var candidates map[string][]string
+	if folders[levelRegion] == "" || folders[levelCity] == "" || folders[levelTown] == "" {
+		var err error
+		if candidates, err = findLayers(fsys); err != nil {
+			return nil, err
+		}
+	}
+
+	layers := make(map[string]layer)
+	for _, level := range []string{levelRegion, levelCity, levelTown} {
+		dir := path.Clean(folders[level])
+		if folders[level] == "" {
+			found := candidates[level]
+			if len(found) == 0 {
+				return nil, &ShapefileError{Path: level + " layer", Record: -1, Err: ErrNoShapefiles}
+			}
+			if len(found) > 1 {
+				return nil, fmt.Errorf("gomuni: more than one %s layer found (%s), set its folder in the Options", level, strings.Join(found, ", "))
+			}
+			dir = found[0]
+		}
+
+		opts.logger().Printf("using %s for the %s layer", dir, level)
+		layers[level] = layer{fsys: fsys, dir: dir}
+	}
+
+	return load(layers[levelRegion], layers[levelCity], layers[levelTown], opts)
+}
+
+//LoadZip loads all the country from a zip archive, like the Limiti_XXXX_WGS84.zip distributed by ISTAT
+func LoadZip(filename string, opts Options) (*Country, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return LoadFS(r, opts)
+}
+
+//LoadZipReader loads all the country from a zip archive read from r
+func LoadZipReader(r io.Reader, opts Options) (*Country, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return LoadFS(zr, opts)
+}
+
+//findLayers walks the file system looking for the directories with the shapefiles of each level,
+//matching the name of the shapefile or of its directory with the layerPrefixes
+func findLayers(fsys fs.FS) (map[string][]string, error) {
+	candidates := make(map[string][]string)
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(path.Ext(p), ".shp") {
+			return nil
+		}
+
+		dir := path.Dir(p)
+		level := layerLevel(path.Base(p))
+		if level == "" {
+			level = layerLevel(path.Base(dir))
+		}
+		if level == "" {
+			return nil
+		}
+
+		found := candidates[level]
+		if len(found) == 0 || found[len(found)-1] != dir {
+			candidates[level] = append(found, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+//layerLevel returns the administrative level of an ISTAT layer name, or an empty string
+func layerLevel(name string) string {
+	name = strings.ToLower(name)
+	for _, level := range []string{levelRegion, levelCity, levelTown} {
+		for _, prefix := range layerPrefixes[level] {
+			if strings.HasPrefix(name, prefix) {
+				return level
+			}
+		}
+	}
+	return ""
+}
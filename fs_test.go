@@ -0,0 +1,88 @@
+package gomuni
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/enrichman/gomuni/internal/fixture"
+)
+
+func Test_LoadFS(t *testing.T) {
+	country, err := LoadFS(fixture.Italy(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(country.Regions) != 2 {
+		t.Fatalf("expected 2 regions, got %d", len(country.Regions))
+	}
+
+	torino := country.GetRegionByID("1").GetCityByID("1")
+	if torino == nil || torino.Shortname != "TO" || !torino.Maincity {
+		t.Fatalf("unexpected city %+v", torino)
+	}
+	if len(torino.Towns) != 2 {
+		t.Fatalf("expected 2 towns, got %d", len(torino.Towns))
+	}
+
+	town := torino.GetTownByID("001156")
+	if town == nil || town.Name != "Moncalieri" || town.Attributes["PRO_COM_T"] != "001156" {
+		t.Errorf("unexpected town %+v", town)
+	}
+	if _, ok := town.Attributes["COMUNE"]; ok {
+		t.Errorf("mapped field COMUNE should not be in the attributes")
+	}
+}
+
+func Test_LoadZipReader(t *testing.T) {
+	fsys := fixture.Italy()
+	names := make([]string, 0, len(fsys))
+	for name := range fsys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(fsys[name].Data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	country, err := LoadZipReader(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if town := country.FindTownByPoint(Point{45, 7.5}); town == nil || town.ID != "001272" {
+		t.Errorf("expected Torino, got %+v", town)
+	}
+}
+
+func Test_LoadFSErrors(t *testing.T) {
+	if _, err := LoadFS(fstest.MapFS{}, Options{}); !errors.Is(err, ErrNoShapefiles) {
+		t.Errorf("expected ErrNoShapefiles, got %v", err)
+	}
+
+	// an unknown schema
+	fsys := fixture.Italy()
+	fixture.Layer{
+		Fields:   []string{"CODE", "NAME"},
+		Features: []fixture.Feature{{Rings: []fixture.Ring{fixture.Box(44, 7, 45, 8)}, Attributes: []string{"1", "Piemonte"}}},
+	}.Add(fsys, "Limiti01012017/Reg01012017/Reg01012017_WGS84")
+
+	_, err := LoadFS(fsys, Options{})
+	var shapefileErr *ShapefileError
+	if !errors.Is(err, ErrUnknownSchema) || !errors.As(err, &shapefileErr) {
+		t.Errorf("expected a ShapefileError with ErrUnknownSchema, got %v", err)
+	}
+}
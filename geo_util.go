@@ -1,64 +1,51 @@
 package gomuni
 
 import (
-	"errors"
 	"math"
-	"unicode"
 )
 
-const (
-	k0 float64 = 0.9996
-	e  float64 = 0.00669438
-	r          = 6378137
-)
-
-var e2 = e * e
-var e3 = e2 * e
-var eP2 = e / (1.0 - e)
-
-var sqrtE = math.Sqrt(1 - e)
-
-var _e = (1 - sqrtE) / (1 + sqrtE)
-var _e2 = _e * _e
-var _e3 = _e2 * _e
-var _e4 = _e3 * _e
-var _e5 = _e4 * _e
-
-var m1 = (1 - e/4 - 3*e2/64 - 5*e3/256)
-var m2 = (3*e/8 + 3*e2/32 + 45*e3/1024)
-var m3 = (15*e2/256 + 45*e3/1024)
-var m4 = (35 * e3 / 3072)
-
-var p2 = (3./2*_e - 27./32*_e3 + 269./512*_e5)
-var p3 = (21./16*_e2 - 55./32*_e4)
-var p4 = (151./96*_e3 - 417./128*_e5)
-var p5 = (1097. / 512 * _e4)
+const k0 float64 = 0.9996
 
 const x = math.Pi / 180
 
 func rad(d float64) float64 { return d * x }
 func deg(r float64) float64 { return r / x }
 
-func toLatLon(easting, northing float64, zoneNumber int, zoneLetter string) (point *Point, err error) {
-
-	x := easting - 500000
-	y := northing
-
-	var northernValue bool
-	if zoneLetter != "" {
-		zoneLetter := unicode.ToUpper(rune(zoneLetter[0]))
-		if !('C' <= zoneLetter && zoneLetter <= 'X') || zoneLetter == 'I' || zoneLetter == 'O' {
-			err := errors.New("zone letter out of range (must be between C and X)")
-			return nil, err
-		}
-		northernValue = (zoneLetter >= 'N')
-	}
+//meridianArc returns the distance along the meridian from the equator to the latitude phi (in radians)
+func meridianArc(ellipsoid Ellipsoid, phi float64) float64 {
+	e := ellipsoid.eccentricity2()
+	e2 := e * e
+	e3 := e2 * e
 
-	if !northernValue {
-		y -= 10000000
-	}
+	return ellipsoid.A * ((1-e/4-3*e2/64-5*e3/256)*phi -
+		(3*e/8+3*e2/32+45*e3/1024)*math.Sin(2*phi) +
+		(15*e2/256+45*e3/1024)*math.Sin(4*phi) -
+		(35*e3/3072)*math.Sin(6*phi))
+}
 
-	m := y / k0
+//inverseTransverseMercator converts the easting and northing, already without the false origin,
+//to latitude and longitude in degrees
+func inverseTransverseMercator(ellipsoid Ellipsoid, easting, northing, centralMeridian, latitudeOfOrigin, scale float64) (float64, float64) {
+	r := ellipsoid.A
+	e := ellipsoid.eccentricity2()
+	e2 := e * e
+	e3 := e2 * e
+	eP2 := e / (1.0 - e)
+
+	sqrtE := math.Sqrt(1 - e)
+	_e := (1 - sqrtE) / (1 + sqrtE)
+	_e2 := _e * _e
+	_e3 := _e2 * _e
+	_e4 := _e3 * _e
+
+	m1 := (1 - e/4 - 3*e2/64 - 5*e3/256)
+
+	p2 := (3./2*_e - 27./32*_e3)
+	p3 := (21./16*_e2 - 55./32*_e4)
+	p4 := (151. / 96 * _e3)
+	p5 := (1097. / 512 * _e4)
+
+	m := meridianArc(ellipsoid, rad(latitudeOfOrigin)) + northing/scale
 	mu := m / (r * m1)
 
 	pRad := (mu +
@@ -77,15 +64,15 @@ func toLatLon(easting, northing float64, zoneNumber int, zoneLetter string) (poi
 	pTan4 := pTan2 * pTan2
 
 	epSin := 1 - e*pSin2
-	epSinSqrt := math.Sqrt(1 - e*pSin2)
+	epSinSqrt := math.Sqrt(epSin)
 
 	n := r / epSinSqrt
 	rad := (1 - e) / epSin
 
-	c := _e * pCos * pCos
+	c := eP2 * pCos * pCos
 	c2 := c * c
 
-	d := x / (n * k0)
+	d := easting / (n * scale)
 	d2 := d * d
 	d3 := d2 * d
 	d4 := d3 * d
@@ -94,14 +81,44 @@ func toLatLon(easting, northing float64, zoneNumber int, zoneLetter string) (poi
 
 	latitude := (pRad - (pTan/rad)*
 		(d2/2-
-			d4/24*(5+3*pTan2+10*c-4*c2-9*eP2)) +
-		d6/720*(61+90*pTan2+298*c+45*pTan4-252*eP2-3*c2))
+			d4/24*(5+3*pTan2+10*c-4*c2-9*eP2)+
+			d6/720*(61+90*pTan2+298*c+45*pTan4-252*eP2-3*c2)))
 
 	longitude := (d -
 		d3/6*(1+2*pTan2+c) +
 		d5/120*(5-2*c+28*pTan2-3*c2+8*eP2+24*pTan4)) / pCos
 
-	return &Point{deg(latitude), deg(longitude) + float64(zoneNumberToCentralLongitude(zoneNumber))}, nil
+	return deg(latitude), deg(longitude) + centralMeridian
+}
+
+//toGeocentric converts the geodetic coordinates (in radians) to geocentric cartesian coordinates
+func toGeocentric(ellipsoid Ellipsoid, phi, lambda float64) (float64, float64, float64) {
+	e := ellipsoid.eccentricity2()
+	sinPhi := math.Sin(phi)
+	n := ellipsoid.A / math.Sqrt(1-e*sinPhi*sinPhi)
+
+	return n * math.Cos(phi) * math.Cos(lambda),
+		n * math.Cos(phi) * math.Sin(lambda),
+		n * (1 - e) * sinPhi
+}
+
+//fromGeocentric converts the geocentric cartesian coordinates to geodetic coordinates (in radians)
+func fromGeocentric(ellipsoid Ellipsoid, x, y, z float64) (float64, float64) {
+	e := ellipsoid.eccentricity2()
+	p := math.Hypot(x, y)
+	lambda := math.Atan2(y, x)
+
+	phi := math.Atan2(z, p*(1-e))
+	for i := 0; i < 10; i++ {
+		sinPhi := math.Sin(phi)
+		n := ellipsoid.A / math.Sqrt(1-e*sinPhi*sinPhi)
+		next := math.Atan2(z+e*n*sinPhi, p)
+		if math.Abs(next-phi) < 1e-12 {
+			return next, lambda
+		}
+		phi = next
+	}
+	return phi, lambda
 }
 
 func zoneNumberToCentralLongitude(zoneNumber int) int {
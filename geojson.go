@@ -0,0 +1,282 @@
+package gomuni
+
+import (
+	"encoding/json"
+	"fmt"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//geoJSON is a GeoJSON object: a geometry, a Feature or a FeatureCollection
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometry    *geoJSON        `json:"geometry,omitempty"`
+	Geometries  []geoJSON       `json:"geometries,omitempty"`
+	Features    []geoJSON       `json:"features,omitempty"`
+}
+
+//ParseGeoJSON returns the MultiPolygon of a GeoJSON Polygon or MultiPolygon.
+//A Feature, a FeatureCollection or a GeometryCollection are merged in a MultiPolygon with all their polygons.
+//The GeoJSON positions are in longitude, latitude order.
+func ParseGeoJSON(data []byte) (MultiPolygon, error) {
+	var obj geoJSON
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGeoJSON, err)
+	}
+
+	m, err := obj.multiPolygon()
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("%w: no polygons", ErrInvalidGeoJSON)
+	}
+	return m, nil
+}
+
+func (obj geoJSON) multiPolygon() (MultiPolygon, error) {
+	switch obj.Type {
+	case "Polygon":
+		var coordinates [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidGeoJSON, err)
+		}
+		p, err := geoJSONPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return MultiPolygon{p}, nil
+
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidGeoJSON, err)
+		}
+		m := make(MultiPolygon, 0, len(coordinates))
+		for _, c := range coordinates {
+			p, err := geoJSONPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, p)
+		}
+		return m, nil
+
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil, fmt.Errorf("%w: feature without geometry", ErrInvalidGeoJSON)
+		}
+		return obj.Geometry.multiPolygon()
+
+	case "FeatureCollection", "GeometryCollection":
+		m := make(MultiPolygon, 0)
+		for _, child := range append(obj.Features, obj.Geometries...) {
+			cm, err := child.multiPolygon()
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, cm...)
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported type %q, expected a Polygon or a MultiPolygon", ErrInvalidGeoJSON, obj.Type)
+}
+
+//geoJSONPolygon converts the rings of a GeoJSON polygon, the first one is the outer ring
+func geoJSONPolygon(coordinates [][][]float64) (Polygon, error) {
+	p := make(Polygon, 0, len(coordinates))
+	for _, positions := range coordinates {
+		if len(positions) < 4 {
+			return nil, fmt.Errorf("%w: a ring needs at least 4 positions", ErrInvalidGeoJSON)
+		}
+		ring := make(Ring, 0, len(positions))
+		for _, position := range positions {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("%w: a position needs the longitude and the latitude", ErrInvalidGeoJSON)
+			}
+			ring = append(ring, Point{Lat: position[1], Lng: position[0]})
+		}
+		p = append(p, ring)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: empty polygon", ErrInvalidGeoJSON)
+	}
+	return p, nil
+}
+
+//Geometry is a boundary as a GeoJSON MultiPolygon. Each polygon is made by its outer ring followed by its holes,
+//as closed rings of [longitude, latitude] positions, counterclockwise the outer ones and clockwise the holes.
+type Geometry struct {
+	Type        string           `json:"type"`
+	Coordinates [][][][2]float64 `json:"coordinates"`
+}
+
+//Geometry returns the MultiPolygon as a GeoJSON MultiPolygon, the rings are copied
+func (m MultiPolygon) Geometry() Geometry {
+	coordinates := make([][][][2]float64, 0, len(m))
+	for _, p := range m {
+		polygon := make([][][2]float64, 0, len(p))
+		for i, ring := range p {
+			if len(ring) == 0 {
+				continue
+			}
+			polygon = append(polygon, geoJSONRing(ring, i == 0))
+		}
+		if len(polygon) > 0 {
+			coordinates = append(coordinates, polygon)
+		}
+	}
+	return Geometry{Type: "MultiPolygon", Coordinates: coordinates}
+}
+
+//geoJSONRing returns the positions of the closed Ring, oriented with the right-hand rule
+//of GeoJSON, the opposite of the shapefiles
+func geoJSONRing(ring Ring, outer bool) [][2]float64 {
+	positions := make([][2]float64, 0, len(ring)+1)
+	for _, p := range ring {
+		positions = append(positions, [2]float64{p.Lng, p.Lat})
+	}
+	if first, last := ring[0], ring[len(ring)-1]; first != last {
+		positions = append(positions, [2]float64{first.Lng, first.Lat})
+	}
+
+	if clockwise := ring.signedArea() < 0; clockwise == outer {
+		for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+			positions[i], positions[j] = positions[j], positions[i]
+		}
+	}
+	return positions
+}
+
+//Feature is a GeoJSON Feature: the boundary of a unit with its fields as properties
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	BBox       []float64              `json:"bbox,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+//FeatureCollection is a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+//NewFeatureCollection returns a FeatureCollection of the Features
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+//Regions is a list of Regions, that can be exported as a GeoJSON FeatureCollection
+type Regions []*Region
+
+//Cities is a list of Cities, that can be exported as a GeoJSON FeatureCollection
+type Cities []*City
+
+//Towns is a list of Towns, that can be exported as a GeoJSON FeatureCollection
+type Towns []*Town
+
+//Feature returns the Region as a GeoJSON Feature, without its Cities
+func (r *Region) Feature() Feature {
+	return newFeature(r.ID, r.properties(), r.polygon, r.BBox)
+}
+
+//Feature returns the City as a GeoJSON Feature, without its Towns
+func (c *City) Feature() Feature {
+	return newFeature(c.ID, c.properties(), c.polygon, c.BBox)
+}
+
+//Feature returns the Town as a GeoJSON Feature
+func (t *Town) Feature() Feature {
+	return newFeature(t.ID, t.properties(), t.polygon, t.BBox)
+}
+
+//properties returns the JSON fields of the Region, without its Cities and its bounding box
+func (r *Region) properties() map[string]interface{} {
+	unit := *r
+	unit.Cities = nil
+	return unitProperties(&unit)
+}
+
+//properties returns the JSON fields of the City, without its Towns and its bounding box
+func (c *City) properties() map[string]interface{} {
+	unit := *c
+	unit.Towns = nil
+	return unitProperties(&unit)
+}
+
+//properties returns the JSON fields of the Town, without its bounding box
+func (t *Town) properties() map[string]interface{} {
+	return unitProperties(t)
+}
+
+//MarshalGeoJSON returns the Region as a GeoJSON Feature
+func (r *Region) MarshalGeoJSON() ([]byte, error) {
+	return json.Marshal(r.Feature())
+}
+
+//MarshalGeoJSON returns the City as a GeoJSON Feature
+func (c *City) MarshalGeoJSON() ([]byte, error) {
+	return json.Marshal(c.Feature())
+}
+
+//MarshalGeoJSON returns the Town as a GeoJSON Feature
+func (t *Town) MarshalGeoJSON() ([]byte, error) {
+	return json.Marshal(t.Feature())
+}
+
+//MarshalGeoJSON returns the Regions as a GeoJSON FeatureCollection
+func (regions Regions) MarshalGeoJSON() ([]byte, error) {
+	features := make([]Feature, len(regions))
+	for i, r := range regions {
+		features[i] = r.Feature()
+	}
+	return json.Marshal(NewFeatureCollection(features))
+}
+
+//MarshalGeoJSON returns the Cities as a GeoJSON FeatureCollection
+func (cities Cities) MarshalGeoJSON() ([]byte, error) {
+	features := make([]Feature, len(cities))
+	for i, c := range cities {
+		features[i] = c.Feature()
+	}
+	return json.Marshal(NewFeatureCollection(features))
+}
+
+//MarshalGeoJSON returns the Towns as a GeoJSON FeatureCollection
+func (towns Towns) MarshalGeoJSON() ([]byte, error) {
+	features := make([]Feature, len(towns))
+	for i, t := range towns {
+		features[i] = t.Feature()
+	}
+	return json.Marshal(NewFeatureCollection(features))
+}
+
+//unitProperties returns the JSON fields of a unit, but the bounding box
+func unitProperties(unit interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	if b, err := json.Marshal(unit); err == nil {
+		json.Unmarshal(b, &properties)
+	}
+	delete(properties, "bbox")
+	return properties
+}
+
+//newFeature returns the Feature of a unit.
+//The bounding box is a member of the Feature, in the west, south, east, north order of GeoJSON.
+func newFeature(id string, properties map[string]interface{}, m MultiPolygon, bbox shp.Box) Feature {
+	geometry := m.Geometry()
+	return Feature{
+		Type:       "Feature",
+		ID:         id,
+		BBox:       []float64{bbox.MinY, bbox.MinX, bbox.MaxY, bbox.MaxX},
+		Geometry:   &geometry,
+		Properties: properties,
+	}
+}
@@ -0,0 +1,58 @@
+package gomuni
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_MarshalGeoJSON(t *testing.T) {
+	country := newTestCountry()
+	region := country.GetRegionByID("8")
+
+	b, err := region.MarshalGeoJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feature Feature
+	if err := json.Unmarshal(b, &feature); err != nil {
+		t.Fatal(err)
+	}
+	if feature.Type != "Feature" || feature.ID != "8" || feature.Properties["name"] != "Emilia-Romagna" {
+		t.Errorf("unexpected feature %s", b)
+	}
+	if _, ok := feature.Properties["cities"]; ok {
+		t.Errorf("the children are in the properties: %s", b)
+	}
+	if len(feature.BBox) != 4 || feature.BBox[0] > feature.BBox[2] || feature.BBox[1] > feature.BBox[3] {
+		t.Errorf("unexpected bbox %v", feature.BBox)
+	}
+
+	// the hole of San Marino is kept
+	polygon, err := ParseGeoJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !polygon.Contains(Point{44.5, 10.5}) || polygon.Contains(Point{45, 11}) {
+		t.Errorf("unexpected geometry %s", b)
+	}
+
+	city := country.GetCityByID("1")
+	b, err = Towns(city.Towns).MarshalGeoJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection FeatureCollection
+	if err := json.Unmarshal(b, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("unexpected collection %s", b)
+	}
+	if p := collection.Features[1].Properties; p["city_id"] != "1" || p["name"] != "Moncalieri" {
+		t.Errorf("unexpected properties %v", p)
+	}
+
+	if b, _ := (Cities{}).MarshalGeoJSON(); string(b) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("unexpected empty collection %s", b)
+	}
+}
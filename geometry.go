@@ -0,0 +1,186 @@
+package gomuni
+
+import (
+	"math"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//Ring is a closed sequence of geolocation points. The last point is connected to the first one.
+type Ring []Point
+
+//Polygon is made by an outer Ring followed by the Rings of its holes
+type Polygon []Ring
+
+//MultiPolygon is a set of disjoint Polygons, like the islands of a Town
+type MultiPolygon []Polygon
+
+//Contains checks if the Point is inside the Ring, using the even-odd rule
+func (r Ring) Contains(point Point) bool {
+	contains := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lng < (b.Lng-a.Lng)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			contains = !contains
+		}
+	}
+	return contains
+}
+
+//signedArea returns the area of the Ring in squared degrees,
+//negative if the Ring is clockwise
+func (r Ring) signedArea() float64 {
+	area := 0.0
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		area += (r[j].Lng - r[i].Lng) * (r[j].Lat + r[i].Lat)
+	}
+	return area / 2
+}
+
+//Contains checks if the Point is inside the outer Ring and outside all the holes
+func (p Polygon) Contains(point Point) bool {
+	if len(p) == 0 || !p[0].Contains(point) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(point) {
+			return false
+		}
+	}
+	return true
+}
+
+//Contains checks if the Point is inside one of the Polygons
+func (m MultiPolygon) Contains(point Point) bool {
+	for _, p := range m {
+		if p.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+//centroid returns the centroid of the Ring and its area in squared degrees
+func (r Ring) centroid() (Point, float64) {
+	var area, lat, lng float64
+	for i := range r {
+		a, b := r[i], r[(i+1)%len(r)]
+		cross := a.Lng*b.Lat - b.Lng*a.Lat
+		area += cross
+		lng += (a.Lng + b.Lng) * cross
+		lat += (a.Lat + b.Lat) * cross
+	}
+	if area == 0 {
+		return r[0], 0
+	}
+	return Point{Lat: lat / (3 * area), Lng: lng / (3 * area)}, math.Abs(area / 2)
+}
+
+//Centroid returns the center of mass of the MultiPolygon, weighting each Polygon by its area without the holes.
+//The centroid of a concave shape can be outside of it.
+func (m MultiPolygon) Centroid() Point {
+	var area, lat, lng float64
+	for _, p := range m {
+		for i, ring := range p {
+			if len(ring) == 0 {
+				continue
+			}
+			c, a := ring.centroid()
+			if i > 0 {
+				a = -a
+			}
+			area += a
+			lat += c.Lat * a
+			lng += c.Lng * a
+		}
+	}
+	if area == 0 {
+		if len(m) == 0 || len(m[0]) == 0 || len(m[0][0]) == 0 {
+			return Point{}
+		}
+		return m[0][0][0]
+	}
+	return Point{Lat: lat / area, Lng: lng / area}
+}
+
+//BBox returns the bounding box of the MultiPolygon, with the latitudes on the X axis
+func (m MultiPolygon) BBox() shp.Box {
+	var bbox shp.Box
+	first := true
+	for _, p := range m {
+		for _, point := range p[0] {
+			if first {
+				bbox = shp.Box{MinX: point.Lat, MinY: point.Lng, MaxX: point.Lat, MaxY: point.Lng}
+				first = false
+				continue
+			}
+			bbox.ExtendWithPoint(shp.Point{X: point.Lat, Y: point.Lng})
+		}
+	}
+	return bbox
+}
+
+//newMultiPolygon groups the rings of a shapefile polygon.
+//As for the shapefile specification the outer rings are clockwise and the holes are counterclockwise,
+//each hole is assigned to the smallest outer ring containing it.
+func newMultiPolygon(rings []Ring) MultiPolygon {
+	groups := groupRings(rings)
+	multiPolygon := make(MultiPolygon, len(groups))
+	for i, group := range groups {
+		multiPolygon[i] = make(Polygon, len(group))
+		for j, r := range group {
+			multiPolygon[i][j] = rings[r]
+		}
+	}
+	return multiPolygon
+}
+
+//groupRings returns the indexes of the rings of each polygon, the outer ring first, as done by newMultiPolygon.
+//The rings with less than 3 points are skipped.
+func groupRings(rings []Ring) [][]int {
+	outers := make([]int, 0, len(rings))
+	holes := make([]int, 0)
+
+	for i, ring := range rings {
+		if len(ring) < 3 {
+			continue
+		}
+		if ring.signedArea() < 0 {
+			outers = append(outers, i)
+		} else {
+			holes = append(holes, i)
+		}
+	}
+
+	// some producers do not respect the orientation, a single ring is always the outer one
+	if len(outers) == 0 && len(holes) == 1 {
+		outers, holes = holes, nil
+	}
+
+	groups := make([][]int, len(outers))
+	for i, outer := range outers {
+		groups[i] = []int{outer}
+	}
+
+	for _, hole := range holes {
+		owner := -1
+		ownerArea := 0.0
+		for i, outer := range outers {
+			area := -rings[outer].signedArea()
+			if rings[outer].Contains(rings[hole][0]) && (owner < 0 || area < ownerArea) {
+				owner = i
+				ownerArea = area
+			}
+		}
+
+		if owner < 0 {
+			// an orphan hole is an island with the wrong orientation
+			groups = append(groups, []int{hole})
+			continue
+		}
+		groups[owner] = append(groups[owner], hole)
+	}
+
+	return groups
+}
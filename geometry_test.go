@@ -0,0 +1,115 @@
+package gomuni
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+//square returns a ring centered in (lat, lng), clockwise when cw is true
+func square(lat, lng, half float64, cw bool) Ring {
+	ring := Ring{
+		{lat - half, lng - half},
+		{lat + half, lng - half},
+		{lat + half, lng + half},
+		{lat - half, lng + half},
+		{lat - half, lng - half},
+	}
+	if !cw {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+	return ring
+}
+
+func Test_newMultiPolygon(t *testing.T) {
+	mainland := square(45, 12, 1, true)
+	enclave := square(45, 12, 0.2, false)
+	island := square(45, 15, 0.5, true)
+
+	polygon := newMultiPolygon([]Ring{mainland, enclave, island})
+	if len(polygon) != 2 {
+		t.Fatalf("expected 2 polygons, got %d", len(polygon))
+	}
+	if len(polygon[0]) != 2 {
+		t.Fatalf("expected the enclave to be a hole of the mainland, got %d rings", len(polygon[0]))
+	}
+
+	tests := []struct {
+		name     string
+		point    Point
+		contains bool
+	}{
+		{"mainland", Point{45.5, 12.5}, true},
+		{"enclave", Point{45, 12}, false},
+		{"island", Point{45.1, 15.1}, true},
+		{"between the parts", Point{45, 13.75}, false},
+		{"sea", Point{40, 10}, false},
+	}
+
+	for _, tt := range tests {
+		if got := polygon.Contains(tt.point); got != tt.contains {
+			t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.point, got, tt.contains)
+		}
+	}
+
+	bbox := polygon.BBox()
+	if bbox.MinX != 44 || bbox.MaxX != 46 || bbox.MinY != 11 || bbox.MaxY != 15.5 {
+		t.Errorf("unexpected bbox %+v", bbox)
+	}
+}
+
+func Test_Centroid(t *testing.T) {
+	tests := []struct {
+		name string
+		m    MultiPolygon
+		want Point
+	}{
+		{"square", MultiPolygon{{square(45, 10, 1, true)}}, Point{45, 10}},
+		{"two squares", MultiPolygon{{square(45, 10, 1, true)}, {square(45, 14, 1, false)}}, Point{45, 12}},
+		// the hole moves the centroid away from it: (16*0 - 4*1) / 12
+		{"hole", MultiPolygon{{square(45, 10, 2, true), square(45, 11, 1, false)}}, Point{45, 10 - 1.0/3}},
+	}
+	for _, tt := range tests {
+		got := tt.m.Centroid()
+		if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lng-tt.want.Lng) > 1e-9 {
+			t.Errorf("%s: Centroid() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_Geometry(t *testing.T) {
+	m := MultiPolygon{{square(45, 10, 2, true), square(45, 11, 1, false)}, {square(45, 14, 1, true)}}
+
+	g := m.Geometry()
+	if g.Type != "MultiPolygon" || len(g.Coordinates) != 2 || len(g.Coordinates[0]) != 2 {
+		t.Fatalf("unexpected geometry %v", g)
+	}
+
+	// the outer ring starts from the south-west corner, counterclockwise with the longitude first
+	outer := g.Coordinates[0][0]
+	if outer[0] != [2]float64{8, 43} || outer[1] != [2]float64{12, 43} || outer[0] != outer[len(outer)-1] {
+		t.Errorf("unexpected outer ring %v", outer)
+	}
+	hole := g.Coordinates[0][1]
+	if hole[1] != [2]float64{10, 46} {
+		t.Errorf("unexpected hole %v", hole)
+	}
+
+	b, _ := json.Marshal(g)
+	parsed, err := ParseGeoJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []Point{{45, 9}, {45, 11}, {45, 14}, {45, 16}} {
+		if parsed.Contains(p) != m.Contains(p) {
+			t.Errorf("Contains(%v) = %v after the round trip", p, parsed.Contains(p))
+		}
+	}
+
+	g.Coordinates[0][0][0] = [2]float64{}
+	if m[0][0][0] != (Point{43, 8}) {
+		t.Errorf("the geometry shares the rings of the MultiPolygon")
+	}
+}
@@ -0,0 +1,175 @@
+//Package fixture builds a tiny ISTAT-like dataset in memory, used by the tests.
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing/fstest"
+)
+
+//WGS84 is the .prj content of the geographic WGS84 coordinates
+const WGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+//Ring is a closed sequence of longitude, latitude coordinates
+type Ring [][2]float64
+
+//Box returns a clockwise rectangular Ring
+func Box(minLat, minLng, maxLat, maxLng float64) Ring {
+	return Ring{
+		{minLng, minLat},
+		{minLng, maxLat},
+		{maxLng, maxLat},
+		{maxLng, minLat},
+		{minLng, minLat},
+	}
+}
+
+//Reverse returns the Ring with the opposite orientation, as used for the holes
+func (r Ring) Reverse() Ring {
+	reversed := make(Ring, len(r))
+	for i, p := range r {
+		reversed[len(r)-1-i] = p
+	}
+	return reversed
+}
+
+//Feature is a shapefile record made by a polygon and its attributes
+type Feature struct {
+	Rings      []Ring
+	Attributes []string
+}
+
+//Layer is a shapefile
+type Layer struct {
+	Fields   []string
+	Features []Feature
+}
+
+//Add writes the .shp, .dbf and .prj files of the Layer in fsys, with the provided base name
+func (l Layer) Add(fsys fstest.MapFS, base string) {
+	fsys[base+".shp"] = &fstest.MapFile{Data: l.shp()}
+	fsys[base+".dbf"] = &fstest.MapFile{Data: l.dbf()}
+	fsys[base+".prj"] = &fstest.MapFile{Data: []byte(WGS84)}
+}
+
+func (l Layer) shp() []byte {
+	var records bytes.Buffer
+	for i, f := range l.Features {
+		var content bytes.Buffer
+		numPoints := 0
+		minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, r := range f.Rings {
+			numPoints += len(r)
+			for _, p := range r {
+				minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+				minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+			}
+		}
+
+		binary.Write(&content, binary.LittleEndian, int32(5))
+		binary.Write(&content, binary.LittleEndian, [4]float64{minX, minY, maxX, maxY})
+		binary.Write(&content, binary.LittleEndian, int32(len(f.Rings)))
+		binary.Write(&content, binary.LittleEndian, int32(numPoints))
+		start := 0
+		for _, r := range f.Rings {
+			binary.Write(&content, binary.LittleEndian, int32(start))
+			start += len(r)
+		}
+		for _, r := range f.Rings {
+			for _, p := range r {
+				binary.Write(&content, binary.LittleEndian, p)
+			}
+		}
+
+		binary.Write(&records, binary.BigEndian, int32(i+1))
+		binary.Write(&records, binary.BigEndian, int32(content.Len()/2))
+		records.Write(content.Bytes())
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, int32(9994))
+	header.Write(make([]byte, 20))
+	binary.Write(&header, binary.BigEndian, int32((100+records.Len())/2))
+	binary.Write(&header, binary.LittleEndian, int32(1000))
+	binary.Write(&header, binary.LittleEndian, int32(5))
+	header.Write(make([]byte, 64))
+
+	return append(header.Bytes(), records.Bytes()...)
+}
+
+const fieldSize = 40
+
+func (l Layer) dbf() []byte {
+	var b bytes.Buffer
+	headerLength := 32 + 32*len(l.Fields) + 1
+	recordLength := 1 + fieldSize*len(l.Fields)
+
+	b.WriteByte(3)
+	b.Write([]byte{120, 1, 1})
+	binary.Write(&b, binary.LittleEndian, int32(len(l.Features)))
+	binary.Write(&b, binary.LittleEndian, int16(headerLength))
+	binary.Write(&b, binary.LittleEndian, int16(recordLength))
+	b.Write(make([]byte, 20))
+
+	for _, f := range l.Fields {
+		name := make([]byte, 11)
+		copy(name, f)
+		b.Write(name)
+		b.WriteByte('C')
+		b.Write(make([]byte, 4))
+		b.WriteByte(fieldSize)
+		b.Write(make([]byte, 15))
+	}
+	b.WriteByte(0x0d)
+
+	for _, f := range l.Features {
+		b.WriteByte(' ')
+		for _, a := range f.Attributes {
+			value := bytes.Repeat([]byte{' '}, fieldSize)
+			copy(value, a)
+			b.Write(value)
+		}
+	}
+	b.WriteByte(0x1a)
+
+	return b.Bytes()
+}
+
+//Italy returns a file system with the layout of an ISTAT archive (vintage 2017) and two regions:
+//Piemonte (01) with the city of Torino (001) split in the towns of Torino (001272) and Moncalieri (001156),
+//and Emilia-Romagna (08) with the city and town of Rimini (099, 099014) surrounding San Marino.
+func Italy() fstest.MapFS {
+	fsys := fstest.MapFS{}
+
+	piemonte := Box(44, 7, 46, 9)
+	emilia := Box(44, 10, 46, 12)
+	sanMarino := Box(44.9, 10.9, 45.1, 11.1).Reverse()
+
+	Layer{
+		Fields: []string{"COD_RIP", "COD_REG", "DEN_REG", "SHAPE_AREA"},
+		Features: []Feature{
+			{[]Ring{piemonte}, []string{"1", "1", "Piemonte", "25387"}},
+			{[]Ring{emilia, sanMarino}, []string{"2", "8", "Emilia-Romagna", "22453"}},
+		},
+	}.Add(fsys, "Limiti01012017/Reg01012017/Reg01012017_WGS84")
+
+	Layer{
+		Fields: []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "COD_PCM", "DEN_PROV", "DEN_CM", "DEN_PCM", "SIGLA"},
+		Features: []Feature{
+			{[]Ring{piemonte}, []string{"1", "1", "1", "201", "201", "-", "Torino", "Torino", "TO"}},
+			{[]Ring{emilia, sanMarino}, []string{"2", "8", "99", "0", "99", "Rimini", "-", "Rimini", "RN"}},
+		},
+	}.Add(fsys, "Limiti01012017/ProvCM01012017/ProvCM01012017_WGS84")
+
+	Layer{
+		Fields: []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "PRO_COM", "PRO_COM_T", "COMUNE", "COMUNE_A"},
+		Features: []Feature{
+			{[]Ring{Box(44, 7, 46, 8)}, []string{"1", "1", "1", "201", "1272", "001272", "Torino", ""}},
+			{[]Ring{Box(44, 8, 46, 9)}, []string{"1", "1", "1", "201", "1156", "001156", "Moncalieri", ""}},
+			{[]Ring{emilia, sanMarino}, []string{"2", "8", "99", "0", "99014", "099014", "Rimini", ""}},
+		},
+	}.Add(fsys, "Limiti01012017/Com01012017/Com01012017_WGS84")
+
+	return fsys
+}
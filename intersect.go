@@ -0,0 +1,189 @@
+package gomuni
+
+import (
+	"math"
+	"sort"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//boxesOverlap checks if two bounding boxes have at least a point in common
+func boxesOverlap(a, b shp.Box) bool {
+	return a.MinX <= b.MaxX && b.MinX <= a.MaxX && a.MinY <= b.MaxY && b.MinY <= a.MaxY
+}
+
+//boxContains checks if the bounding box a contains b
+func boxContains(a, b shp.Box) bool {
+	return a.MinX <= b.MinX && b.MaxX <= a.MaxX && a.MinY <= b.MinY && b.MaxY <= a.MaxY
+}
+
+//BoxPolygon returns the MultiPolygon of a bounding box, with the latitudes on the X axis
+func BoxPolygon(bbox shp.Box) MultiPolygon {
+	return MultiPolygon{{Ring{
+		{Lat: bbox.MinX, Lng: bbox.MinY},
+		{Lat: bbox.MaxX, Lng: bbox.MinY},
+		{Lat: bbox.MaxX, Lng: bbox.MaxY},
+		{Lat: bbox.MinX, Lng: bbox.MaxY},
+	}}}
+}
+
+//Intersects checks if the MultiPolygons have at least a point in common:
+//their boundaries cross, or one of them is inside the other one
+func (m MultiPolygon) Intersects(other MultiPolygon) bool {
+	bbox, otherBBox := m.BBox(), other.BBox()
+	if len(m) == 0 || len(other) == 0 || !boxesOverlap(bbox, otherBBox) {
+		return false
+	}
+
+	for _, p := range m {
+		for _, ring := range p {
+			if len(ring) > 0 && other.Contains(ring[0]) {
+				return true
+			}
+		}
+	}
+	for _, p := range other {
+		for _, ring := range p {
+			if len(ring) > 0 && m.Contains(ring[0]) {
+				return true
+			}
+		}
+	}
+
+	for _, p := range m {
+		for _, ring := range p {
+			for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+				a, b := ring[j], ring[i]
+				edge := shp.Box{MinX: math.Min(a.Lat, b.Lat), MinY: math.Min(a.Lng, b.Lng), MaxX: math.Max(a.Lat, b.Lat), MaxY: math.Max(a.Lng, b.Lng)}
+				if boxesOverlap(edge, otherBBox) && other.crosses(a, b) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+//crosses checks if the segment between a and b crosses one of the edges of the MultiPolygon
+func (m MultiPolygon) crosses(a, b Point) bool {
+	for _, p := range m {
+		for _, ring := range p {
+			for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+				if segmentsIntersect(a, b, ring[j], ring[i]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+//segmentsIntersect checks if the segment p1-p2 intersects the segment p3-p4, touching included
+func segmentsIntersect(p1, p2, p3, p4 Point) bool {
+	d1 := orientation(p3, p4, p1)
+	d2 := orientation(p3, p4, p2)
+	d3 := orientation(p1, p2, p3)
+	d4 := orientation(p1, p2, p4)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(p3, p4, p1)) || (d2 == 0 && onSegment(p3, p4, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, p3)) || (d4 == 0 && onSegment(p1, p2, p4))
+}
+
+//orientation returns the cross product of b-a and c-a, positive if a, b, c turn counterclockwise
+func orientation(a, b, c Point) float64 {
+	return (b.Lng-a.Lng)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lng-a.Lng)
+}
+
+//onSegment checks if the point c, collinear with a and b, is between them
+func onSegment(a, b, c Point) bool {
+	return math.Min(a.Lat, b.Lat) <= c.Lat && c.Lat <= math.Max(a.Lat, b.Lat) && math.Min(a.Lng, b.Lng) <= c.Lng && c.Lng <= math.Max(a.Lng, b.Lng)
+}
+
+//RegionsInBBox returns the Regions intersecting the bounding box, with the latitudes on the X axis
+func (c *Country) RegionsInBBox(bbox shp.Box) []*Region {
+	return c.regionsIntersecting(BoxPolygon(bbox), true)
+}
+
+//RegionsIntersecting returns the Regions intersecting the MultiPolygon, sorted by ID
+func (c *Country) RegionsIntersecting(polygon MultiPolygon) []*Region {
+	return c.regionsIntersecting(polygon, false)
+}
+
+//regionsIntersecting returns the Regions intersecting the MultiPolygon.
+//If the MultiPolygon is a box the Regions inside its bounding box are taken without checking their polygons.
+func (c *Country) regionsIntersecting(polygon MultiPolygon, isBox bool) []*Region {
+	bbox := polygon.BBox()
+	regions := make([]*Region, 0)
+	for _, i := range c.regionsIndex.search(bbox) {
+		if r := c.Regions[i]; (isBox && boxContains(bbox, r.BBox)) || r.polygon.Intersects(polygon) {
+			regions = append(regions, r)
+		}
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return lessID(regions[i].ID, regions[j].ID) })
+	return regions
+}
+
+//CitiesInBBox returns the Cities intersecting the bounding box, with the latitudes on the X axis
+func (c *Country) CitiesInBBox(bbox shp.Box) []*City {
+	return c.citiesIntersecting(BoxPolygon(bbox), true)
+}
+
+//CitiesIntersecting returns the Cities intersecting the MultiPolygon, sorted by ID
+func (c *Country) CitiesIntersecting(polygon MultiPolygon) []*City {
+	return c.citiesIntersecting(polygon, false)
+}
+
+//citiesIntersecting returns the Cities intersecting the MultiPolygon.
+//If the MultiPolygon is a box the Cities inside its bounding box are taken without checking their polygons.
+func (c *Country) citiesIntersecting(polygon MultiPolygon, isBox bool) []*City {
+	bbox := polygon.BBox()
+	cities := make([]*City, 0)
+	for _, i := range c.regionsIndex.search(bbox) {
+		r := c.Regions[i]
+		for _, j := range r.citiesIndex.search(bbox) {
+			if city := r.Cities[j]; (isBox && boxContains(bbox, city.BBox)) || city.polygon.Intersects(polygon) {
+				cities = append(cities, city)
+			}
+		}
+	}
+
+	sort.Slice(cities, func(i, j int) bool { return lessID(cities[i].ID, cities[j].ID) })
+	return cities
+}
+
+//TownsInBBox returns the Towns intersecting the bounding box, with the latitudes on the X axis
+func (c *Country) TownsInBBox(bbox shp.Box) []*Town {
+	return c.townsIntersecting(BoxPolygon(bbox), true)
+}
+
+//TownsIntersecting returns the Towns intersecting the MultiPolygon, sorted by ID
+func (c *Country) TownsIntersecting(polygon MultiPolygon) []*Town {
+	return c.townsIntersecting(polygon, false)
+}
+
+//townsIntersecting returns the Towns intersecting the MultiPolygon.
+//If the MultiPolygon is a box the Towns inside its bounding box are taken without checking their polygons.
+func (c *Country) townsIntersecting(polygon MultiPolygon, isBox bool) []*Town {
+	bbox := polygon.BBox()
+	towns := make([]*Town, 0)
+	for _, t := range c.townsInBox(bbox) {
+		if (isBox && boxContains(bbox, t.BBox)) || t.polygon.Intersects(polygon) {
+			towns = append(towns, t)
+		}
+	}
+
+	sort.Slice(towns, func(i, j int) bool { return lessID(towns[i].ID, towns[j].ID) })
+	return towns
+}
+
+//lessID compares the numeric ISTAT codes, with or without the leading zeros
+func lessID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
@@ -0,0 +1,103 @@
+package gomuni
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+func Test_Intersects(t *testing.T) {
+	big := MultiPolygon{{square(45, 10, 2, true), square(45, 10, 1, false)}}
+
+	tests := []struct {
+		name  string
+		other MultiPolygon
+		want  bool
+	}{
+		{"crossing", MultiPolygon{{square(47, 12, 0.5, true)}}, true},
+		{"inside", MultiPolygon{{square(43.5, 8.5, 0.2, true)}}, true},
+		{"containing", MultiPolygon{{square(45, 10, 5, true)}}, true},
+		{"in the hole", MultiPolygon{{square(45, 10, 0.5, true)}}, false},
+		{"outside", MultiPolygon{{square(50, 10, 1, true)}}, false},
+		{"in the bbox only", MultiPolygon{{Ring{{46.9, 7.5}, {47.5, 8.1}, {47.5, 7.5}}}}, false},
+		{"touching", MultiPolygon{{square(48, 10, 1, true)}}, true},
+	}
+	for _, tt := range tests {
+		if got := big.Intersects(tt.other); got != tt.want {
+			t.Errorf("%s: Intersects() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.other.Intersects(big); got != tt.want {
+			t.Errorf("%s: reversed Intersects() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_TownsInBBox(t *testing.T) {
+	country := newTestCountry()
+
+	tests := []struct {
+		name    string
+		bbox    shp.Box
+		regions []string
+		cities  []string
+		towns   []string
+	}{
+		{"Torino", shp.Box{MinX: 44.5, MinY: 7.2, MaxX: 45.5, MaxY: 7.8}, []string{"1"}, []string{"1"}, []string{"001272"}},
+		{"Torino and Moncalieri", shp.Box{MinX: 44.5, MinY: 7.5, MaxX: 45.5, MaxY: 8.5}, []string{"1"}, []string{"1"}, []string{"001156", "001272"}},
+		{"everything", shp.Box{MinX: 40, MinY: 5, MaxX: 50, MaxY: 15}, []string{"1", "8"}, []string{"1", "99"}, []string{"001156", "001272", "099014"}},
+		{"San Marino", shp.Box{MinX: 44.95, MinY: 10.95, MaxX: 45.05, MaxY: 11.05}, []string{}, []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions := make([]string, 0)
+			for _, r := range country.RegionsInBBox(tt.bbox) {
+				regions = append(regions, r.ID)
+			}
+			cities := make([]string, 0)
+			for _, c := range country.CitiesInBBox(tt.bbox) {
+				cities = append(cities, c.ID)
+			}
+			towns := make([]string, 0)
+			for _, town := range country.TownsInBBox(tt.bbox) {
+				towns = append(towns, town.ID)
+			}
+
+			if strings.Join(regions, ",") != strings.Join(tt.regions, ",") {
+				t.Errorf("RegionsInBBox() = %v, want %v", regions, tt.regions)
+			}
+			if strings.Join(cities, ",") != strings.Join(tt.cities, ",") {
+				t.Errorf("CitiesInBBox() = %v, want %v", cities, tt.cities)
+			}
+			if strings.Join(towns, ",") != strings.Join(tt.towns, ",") {
+				t.Errorf("TownsInBBox() = %v, want %v", towns, tt.towns)
+			}
+		})
+	}
+}
+
+func Test_TownsIntersecting(t *testing.T) {
+	country := newTestCountry()
+
+	// a triangle from Torino to Rimini, crossing Moncalieri
+	polygon, err := ParseGeoJSON([]byte(`{"type": "Feature", "geometry": {"type": "Polygon",
+		"coordinates": [[[7.5, 45], [11.5, 44.5], [7.5, 43.5], [7.5, 45]]]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	towns := make([]string, 0)
+	for _, town := range country.TownsIntersecting(polygon) {
+		towns = append(towns, town.ID)
+	}
+	if strings.Join(towns, ",") != "001156,001272,099014" {
+		t.Errorf("TownsIntersecting() = %v", towns)
+	}
+
+	for _, invalid := range []string{`{"type": "Point", "coordinates": [7, 45]}`, `{"type": "Polygon", "coordinates": [[[7, 45]]]}`, `[`} {
+		if _, err := ParseGeoJSON([]byte(invalid)); !errors.Is(err, ErrInvalidGeoJSON) {
+			t.Errorf("ParseGeoJSON(%s) error = %v, want ErrInvalidGeoJSON", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package gomuni
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//Load all the country with the Regions, Cities and Towns.
+//It panics if the shapefiles cannot be loaded, use LoadWithOptions to handle the error.
+func Load(regionFolder, cityFolder, townFolder string) *Country {
+	country, err := LoadWithOptions(Options{
+		RegionFolder: regionFolder,
+		CityFolder:   cityFolder,
+		TownFolder:   townFolder,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return country
+}
+
+//LoadWithOptions loads all the country with the Regions, Cities and Towns found in the configured folders
+func LoadWithOptions(opts Options) (*Country, error) {
+	return load(osLayer(opts.RegionFolder), osLayer(opts.CityFolder), osLayer(opts.TownFolder), opts)
+}
+
+func load(regions, cities, towns layer, opts Options) (*Country, error) {
+	country, err := loadCountryWithRegions(regions, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := country.loadRegionsWithCities(cities, opts); err != nil {
+		return nil, err
+	}
+	if err := country.loadCitiesWithTowns(towns, opts); err != nil {
+		return nil, err
+	}
+	country.buildIndexes()
+	country.buildSpatialIndexes()
+	return country, nil
+}
+
+func loadCountryWithRegions(l layer, opts Options) (*Country, error) {
+	country := newCountry()
+
+	err := readLayer(l, levelRegion, opts, func(schema Schema, record map[string]string, polygon MultiPolygon) error {
+		reg := &Region{
+			ID:         record[schema.ID],
+			Name:       record[schema.Name],
+			Names:      buildNames(record[schema.Name], record[schema.AltName], record[schema.ID]),
+			Attributes: schema.attributes(record),
+			Cities:     make([]*City, 0),
+			citiesMap:  make(map[string]*City),
+		}
+
+		reg.BBox = polygon.BBox()
+		reg.polygon = polygon
+
+		country.addRegion(reg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts.logger().Printf("loaded %d regions from %s", len(country.Regions), l)
+	return country, nil
+}
+
+func (c *Country) loadRegionsWithCities(l layer, opts Options) error {
+	count := 0
+
+	err := readLayer(l, levelCity, opts, func(schema Schema, record map[string]string, polygon MultiPolygon) error {
+		regID := record[schema.RegionID]
+		cityID := record[schema.ID]
+
+		city := &City{
+			RegionID:   regID,
+			ID:         cityID,
+			Name:       record[schema.Name],
+			Names:      buildNames(record[schema.Name], record[schema.AltName], regID),
+			Shortname:  record[schema.Shortname],
+			Maincity:   schema.maincity(record),
+			Attributes: schema.attributes(record),
+			Towns:      make([]*Town, 0),
+			townsMap:   make(map[string]*Town),
+		}
+
+		city.BBox = polygon.BBox()
+		city.polygon = polygon
+
+		region := c.GetRegionByID(regID)
+		if region == nil {
+			return &OrphanError{Level: "city", ID: cityID, ParentID: regID}
+		}
+		region.addCity(city)
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	opts.logger().Printf("loaded %d cities from %s", count, l)
+	return nil
+}
+
+func (c *Country) loadCitiesWithTowns(l layer, opts Options) error {
+	count := 0
+
+	err := readLayer(l, levelTown, opts, func(schema Schema, record map[string]string, polygon MultiPolygon) error {
+		regID := record[schema.RegionID]
+		cityID := record[schema.CityID]
+
+		town := &Town{
+			ID:         buildIstatID(record[schema.ID]),
+			RegionID:   regID,
+			CityID:     cityID,
+			Name:       record[schema.Name],
+			Names:      buildNames(record[schema.Name], record[schema.AltName], regID),
+			Attributes: schema.attributes(record),
+		}
+
+		town.BBox = polygon.BBox()
+		town.polygon = polygon
+
+		region := c.GetRegionByID(regID)
+		if region == nil {
+			return &OrphanError{Level: "town", ID: town.ID, ParentID: regID}
+		}
+		city := region.GetCityByID(cityID)
+		if city == nil {
+			return &OrphanError{Level: "town", ID: town.ID, ParentID: cityID}
+		}
+		city.addTown(town)
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	opts.logger().Printf("loaded %d towns from %s", count, l)
+	return nil
+}
+
+//recordFunc is called for every polygon of a layer, with its DBF record keyed by field name
+type recordFunc func(schema Schema, record map[string]string, polygon MultiPolygon) error
+
+//layer is a directory of a file system holding the shapefiles of an administrative level
+type layer struct {
+	fsys fs.FS
+	dir  string
+	//root prefixes the paths reported in the errors
+	root string
+}
+
+//osLayer returns the layer of a folder of the local file system
+func osLayer(folder string) layer {
+	return layer{fsys: os.DirFS(folder), dir: ".", root: folder}
+}
+
+func (l layer) String() string {
+	return path.Join(l.root, l.dir)
+}
+
+//readLayer calls fn for every polygon of every shapefile found in the layer
+func readLayer(l layer, level string, opts Options, fn recordFunc) error {
+	files, err := fs.ReadDir(l.fsys, l.dir)
+	if err != nil {
+		return &ShapefileError{Path: l.String(), Record: -1, Err: err}
+	}
+
+	loaded := false
+	for _, f := range files {
+		if f.IsDir() || !strings.EqualFold(path.Ext(f.Name()), ".shp") {
+			continue
+		}
+
+		if err := readShapefile(l, f.Name(), level, opts, fn); err != nil {
+			return err
+		}
+		loaded = true
+	}
+
+	if !loaded {
+		return &ShapefileError{Path: l.String(), Record: -1, Err: ErrNoShapefiles}
+	}
+	return nil
+}
+
+func readShapefile(l layer, name, level string, opts Options, fn recordFunc) error {
+	base := path.Join(l.dir, strings.TrimSuffix(name, path.Ext(name)))
+	shpPath := path.Join(l.root, l.dir, name)
+
+	projection, err := readProjection(l, base, opts)
+	if err != nil {
+		return err
+	}
+
+	data, err := fs.ReadFile(l.fsys, path.Join(l.dir, name))
+	if err != nil {
+		return &ShapefileError{Path: shpPath, Record: -1, Err: err}
+	}
+	records, err := decodeShp(data)
+	if err != nil {
+		return &ShapefileError{Path: shpPath, Record: -1, Err: err}
+	}
+
+	dbfData, err := readSidecar(l.fsys, base, ".dbf")
+	if err != nil {
+		return &ShapefileError{Path: path.Join(l.root, base+".dbf"), Record: -1, Err: err}
+	}
+	table, err := decodeDbf(dbfData)
+	if err != nil {
+		return &ShapefileError{Path: path.Join(l.root, base+".dbf"), Record: -1, Err: err}
+	}
+
+	schema, err := detectSchema(level, table.fields, opts)
+	if err != nil {
+		return &ShapefileError{Path: shpPath, Record: -1, Err: err}
+	}
+
+	for _, r := range records {
+		if r.n >= len(table.records) {
+			return &ShapefileError{Path: shpPath, Record: r.n, Err: errors.New("missing DBF record")}
+		}
+
+		record := make(map[string]string, len(table.fields))
+		for i, f := range table.fields {
+			record[f] = table.records[r.n][i]
+		}
+
+		polygon, err := projectPolygon(r.polygon, projection)
+		if err == nil {
+			err = fn(schema, record, polygon)
+		}
+		if err != nil {
+			if _, orphan := err.(*OrphanError); orphan {
+				return err
+			}
+			return &ShapefileError{Path: shpPath, Record: r.n, Err: err}
+		}
+	}
+	return nil
+}
+
+//readSidecar reads the file with the same base name of the shapefile and the provided extension,
+//in lower or upper case
+func readSidecar(fsys fs.FS, base, ext string) ([]byte, error) {
+	data, err := fs.ReadFile(fsys, base+ext)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadFile(fsys, base+strings.ToUpper(ext))
+	}
+	return data, err
+}
+
+//readProjection parses the .prj file next to the shapefile.
+//If it does not exist the Projection of the Options is used.
+func readProjection(l layer, base string, opts Options) (Projection, error) {
+	prjPath := path.Join(l.root, base+".prj")
+
+	wkt, err := readSidecar(l.fsys, base, ".prj")
+	if errors.Is(err, fs.ErrNotExist) {
+		opts.logger().Printf("%s not found, using the default projection", prjPath)
+		return opts.projection(), nil
+	}
+	if err != nil {
+		return nil, &ShapefileError{Path: prjPath, Record: -1, Err: err}
+	}
+
+	projection, err := ParsePRJ(string(wkt))
+	if err != nil {
+		return nil, &ShapefileError{Path: prjPath, Record: -1, Err: err}
+	}
+	return projection, nil
+}
+
+//projectPolygon converts the shapefile polygon to a MultiPolygon of geolocation coordinates,
+//splitting its points into rings with the part indices
+func projectPolygon(p *shp.Polygon, projection Projection) (MultiPolygon, error) {
+	rings := make([]Ring, 0, len(p.Parts))
+	for i, start := range p.Parts {
+		end := int32(len(p.Points))
+		if i+1 < len(p.Parts) {
+			end = p.Parts[i+1]
+		}
+		if start < 0 || start > end || end > int32(len(p.Points)) {
+			return nil, fmt.Errorf("invalid part index %d", start)
+		}
+
+		ring := make(Ring, 0, end-start)
+		for _, point := range p.Points[start:end] {
+			point, err := projection.ToLatLon(point.X, point.Y)
+			if err != nil {
+				return nil, err
+			}
+			ring = append(ring, point)
+		}
+		rings = append(rings, ring)
+	}
+
+	return newMultiPolygon(rings), nil
+}
+
+func buildIstatID(id string) string {
+	for len(id) < 6 {
+		id = "0" + id
+	}
+	return id
+}
@@ -0,0 +1,79 @@
+package gomuni
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/enrichman/gomuni/internal/fixture"
+)
+
+//writeFolders writes the files of fsys in a temporary folder, returning the options loading its layers
+func writeFolders(t *testing.T, fsys fstest.MapFS) Options {
+	dir := t.TempDir()
+	for name, f := range fsys {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := filepath.Join(dir, "Limiti01012017")
+	return Options{
+		RegionFolder: filepath.Join(base, "Reg01012017"),
+		CityFolder:   filepath.Join(base, "ProvCM01012017"),
+		TownFolder:   filepath.Join(base, "Com01012017"),
+	}
+}
+
+func Test_LoadWithOptions(t *testing.T) {
+	country, err := LoadWithOptions(writeFolders(t, fixture.Italy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if town := country.FindTownByPoint(Point{45, 8.5}); town == nil || town.ID != "001156" {
+		t.Errorf("expected Moncalieri, got %+v", town)
+	}
+}
+
+func Test_LoadWithOptionsErrors(t *testing.T) {
+	opts := writeFolders(t, fixture.Italy())
+
+	empty := opts
+	empty.CityFolder = t.TempDir()
+	if _, err := LoadWithOptions(empty); !errors.Is(err, ErrNoShapefiles) {
+		t.Errorf("expected ErrNoShapefiles, got %v", err)
+	}
+
+	missing := opts
+	missing.RegionFolder = filepath.Join(t.TempDir(), "missing")
+	_, err := LoadWithOptions(missing)
+	var shapefileErr *ShapefileError
+	if !errors.As(err, &shapefileErr) || shapefileErr.Record != -1 || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a ShapefileError wrapping fs.ErrNotExist, got %v", err)
+	} else if !strings.Contains(err.Error(), "missing") {
+		t.Errorf("the error %q does not name the folder", err)
+	}
+
+	// a town referencing a missing city
+	fsys := fixture.Italy()
+	fixture.Layer{
+		Fields:   []string{"COD_REG", "COD_PROV", "PRO_COM", "COMUNE"},
+		Features: []fixture.Feature{{Rings: []fixture.Ring{fixture.Box(44, 7, 45, 8)}, Attributes: []string{"1", "2", "2001", "Orphan"}}},
+	}.Add(fsys, "Limiti01012017/Com01012017/Com01012017_WGS84")
+
+	_, err = LoadWithOptions(writeFolders(t, fsys))
+	var orphan *OrphanError
+	if !errors.As(err, &orphan) || orphan.Level != "town" || orphan.ID != "002001" || orphan.ParentID != "2" {
+		t.Errorf("expected an OrphanError, got %v", err)
+	} else if err.Error() != "gomuni: town 002001 references unknown parent 2" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
@@ -0,0 +1,139 @@
+package gomuni
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Names are the official names of a unit keyed by language, like "it" and "de" for Bolzano/Bozen
+type Names map[string]string
+
+//regionLanguages are the second official language of the ISTAT names of the bilingual regions:
+//Valle d'Aosta/Vallée d'Aoste, Trentino-Alto Adige/Südtirol and Friuli-Venezia Giulia
+var regionLanguages = map[string]string{
+	"2": "fr",
+	"4": "de",
+	"6": "sl",
+}
+
+//buildNames returns the Names of a unit from its ISTAT name, like "Bolzano/Bozen", and from the
+//name in the other language, if any. The language of the other names is the one of the region.
+func buildNames(name, altName, regionID string) Names {
+	names := Names{}
+	lang := regionLanguages[strings.TrimLeft(regionID, "0")]
+
+	parts := strings.Split(name, "/")
+	names["it"] = strings.TrimSpace(parts[0])
+	if lang == "" {
+		return names
+	}
+
+	for _, alt := range append(parts[1:], strings.Split(altName, "/")...) {
+		if alt = strings.TrimSpace(alt); alt != "" && alt != names["it"] && names[lang] == "" {
+			names[lang] = alt
+		}
+	}
+	return names
+}
+
+//Preferred returns the name in the first of the languages found, like "de" or "de-AT".
+//It returns false if there is no name in any of the languages.
+func (n Names) Preferred(langs ...string) (string, bool) {
+	for _, lang := range langs {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if name, ok := n[lang]; ok {
+			return name, true
+		}
+		if i := strings.IndexAny(lang, "-_"); i > 0 {
+			if name, ok := n[lang[:i]]; ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+//LocalizedName returns the name of the Region in the first of the languages found, or its Name
+func (r *Region) LocalizedName(langs ...string) string {
+	if name, ok := r.Names.Preferred(langs...); ok {
+		return name
+	}
+	return r.Name
+}
+
+//LocalizedName returns the name of the City in the first of the languages found, or its Name
+func (c *City) LocalizedName(langs ...string) string {
+	if name, ok := c.Names.Preferred(langs...); ok {
+		return name
+	}
+	return c.Name
+}
+
+//LocalizedName returns the name of the Town in the first of the languages found, or its Name
+func (t *Town) LocalizedName(langs ...string) string {
+	if name, ok := t.Names.Preferred(langs...); ok {
+		return name
+	}
+	return t.Name
+}
+
+//LoadNames adds the localized names read from a CSV with the columns level, id, lang and name,
+//like "town,021008,lld,Bulsan". The level is region, city or town, the first line is the header.
+//The units not found are skipped, the names are searchable with SearchByName.
+func (c *Country) LoadNames(r io.Reader) error {
+	reader, err := newCSVReader(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := reader.Read(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNames, err)
+	}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidNames, err)
+		}
+		if len(row) < 4 {
+			return fmt.Errorf("%w: line %d: expected level, id, lang and name", ErrInvalidNames, line)
+		}
+
+		level, id := strings.ToLower(strings.TrimSpace(row[0])), strings.TrimSpace(row[1])
+		lang, name := strings.ToLower(strings.TrimSpace(row[2])), strings.TrimSpace(row[3])
+		if lang == "" || name == "" {
+			continue
+		}
+
+		var names *Names
+		switch level {
+		case levelRegion:
+			if region := c.GetRegionByID(id); region != nil {
+				names = &region.Names
+			}
+		case levelCity:
+			if city := c.GetCityByID(id); city != nil {
+				names = &city.Names
+			}
+		case levelTown:
+			if town := c.GetTownByID(id); town != nil {
+				names = &town.Names
+			}
+		default:
+			return fmt.Errorf("%w: line %d: unknown level %q", ErrInvalidNames, line, row[0])
+		}
+
+		if names != nil {
+			if *names == nil {
+				*names = Names{}
+			}
+			(*names)[lang] = name
+		}
+	}
+
+	c.buildIndexes()
+	return nil
+}
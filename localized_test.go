@@ -0,0 +1,71 @@
+package gomuni
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_buildNames(t *testing.T) {
+	tests := []struct {
+		name, altName, regionID string
+		want                    Names
+	}{
+		{"Torino", "", "1", Names{"it": "Torino"}},
+		{"Bolzano/Bozen", "", "4", Names{"it": "Bolzano", "de": "Bozen"}},
+		{"Bolzano", "Bozen", "04", Names{"it": "Bolzano", "de": "Bozen"}},
+		{"Aosta", "Aoste", "2", Names{"it": "Aosta", "fr": "Aoste"}},
+		{"Doberdò del Lago/Doberdob", "", "6", Names{"it": "Doberdò del Lago", "sl": "Doberdob"}},
+		{"Valle d'Aosta/Vallée d'Aoste", "", "2", Names{"it": "Valle d'Aosta", "fr": "Vallée d'Aoste"}},
+	}
+	for _, tt := range tests {
+		if got := buildNames(tt.name, tt.altName, tt.regionID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("buildNames(%q, %q, %q) = %v, want %v", tt.name, tt.altName, tt.regionID, got, tt.want)
+		}
+	}
+}
+
+func Test_Preferred(t *testing.T) {
+	names := Names{"it": "Bolzano", "de": "Bozen"}
+
+	tests := []struct {
+		langs []string
+		want  string
+		found bool
+	}{
+		{[]string{"de"}, "Bozen", true},
+		{[]string{"de-AT", "it"}, "Bozen", true},
+		{[]string{"fr", "IT"}, "Bolzano", true},
+		{[]string{"fr"}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		if got, found := names.Preferred(tt.langs...); got != tt.want || found != tt.found {
+			t.Errorf("Preferred(%v) = %q, %v, want %q, %v", tt.langs, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func Test_LoadNames(t *testing.T) {
+	country := newTestCountry()
+
+	csv := "level,id,lang,name\ntown,1272,pms,Turin\ncity,1,fr,Turin\nregion,8,eml,Emégglia-Rumâgna\ntown,999999,fr,Nowhere\n"
+	if err := country.LoadNames(strings.NewReader(csv)); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := country.GetTownByID("001272").LocalizedName("pms", "it"); name != "Turin" {
+		t.Errorf("LocalizedName(pms) = %q, want Turin", name)
+	}
+	if name := country.GetTownByID("001272").LocalizedName("de"); name != "Torino" {
+		t.Errorf("LocalizedName(de) = %q, want the Name", name)
+	}
+	if matches := country.SearchByName("Emegglia Rumagna", 1); len(matches) == 0 || matches[0].Region.ID != "8" || matches[0].Score != 1 {
+		t.Errorf("SearchByName() of a localized name = %+v, want Emilia-Romagna", matches)
+	}
+
+	if err := country.LoadNames(strings.NewReader("level,id,lang,name\nstate,1,it,Italia\n")); !errors.Is(err, ErrInvalidNames) {
+		t.Errorf("LoadNames() with an unknown level error = %v, want ErrInvalidNames", err)
+	}
+}
@@ -0,0 +1,304 @@
+package gomuni
+
+import (
+	"math"
+	"strconv"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//TileExtent is the size of a Mapbox Vector Tile in its own coordinates
+const TileExtent = 4096
+
+//tileBuffer is the margin of the tile kept around the clipped polygons, in tile coordinates,
+//so that their borders are not drawn on the edges of the tile
+const tileBuffer = 64
+
+//maxLatitude is the latitude of the edges of the Web Mercator tiles
+const maxLatitude = 85.0511287798
+
+//The fields of the Mapbox Vector Tile protocol buffers
+const (
+	mvtTileLayers    = 3
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+	mvtLayerVersion  = 15
+	mvtFeatureID     = 1
+	mvtFeatureTags   = 2
+	mvtFeatureType   = 3
+	mvtFeatureGeom   = 4
+	mvtValueString   = 1
+	mvtPolygon       = 3
+	mvtMoveTo        = 1
+	mvtLineTo        = 2
+	mvtClosePath     = 7
+)
+
+//MarshalMVT returns the tile z/x/y of the Topology as a Mapbox Vector Tile with the regions, cities and towns layers.
+//Each unit has its id and name properties. The arcs are simplified to the size of a point of the tile,
+//once for all the units sharing them, and the polygons are clipped to the tile. An empty tile has no bytes.
+//It is nil if the Topology was not returned by Country.Topology.
+func (t *Topology) MarshalMVT(z, x, y int) []byte {
+	if t.index == nil {
+		return nil
+	}
+	tile := newTileProjection(z, x, y)
+	// the arcs are simplified once for the units sharing them, nil if not yet needed
+	simplified := make([]Ring, len(t.index.arcs))
+
+	encoded := make([]byte, 0)
+	for _, layer := range []string{TopologyRegions, TopologyCities, TopologyTowns} {
+		if b := t.tileLayer(tile, layer, simplified); b != nil {
+			encoded = appendField(encoded, mvtTileLayers, b)
+		}
+	}
+	return encoded
+}
+
+//tileLayer returns the encoded layer of the tile, nil if no unit of the layer is in the tile
+func (t *Topology) tileLayer(tile tileProjection, layer string, simplified []Ring) []byte {
+	features := make([]byte, 0)
+	values := make([]string, 0)
+	valueIndex := make(map[string]int)
+	value := func(v string) uint64 {
+		i, ok := valueIndex[v]
+		if !ok {
+			i = len(values)
+			valueIndex[v] = i
+			values = append(values, v)
+		}
+		return uint64(i)
+	}
+
+	for _, i := range t.index.spatial[layer].search(tile.box()) {
+		g := t.index.geometries[layer][i]
+		for _, polygon := range g.Arcs {
+			for _, ring := range polygon {
+				for _, index := range ring {
+					if index < 0 {
+						index = ^index
+					}
+					if simplified[index] == nil {
+						simplified[index] = tile.simplify(t.index.arcs[index])
+					}
+				}
+			}
+		}
+		geometry := tile.geometry(g.Arcs, simplified)
+		if geometry == nil {
+			continue
+		}
+
+		name, _ := g.Properties["name"].(string)
+		feature := make([]byte, 0)
+		if id, err := strconv.ParseUint(g.ID, 10, 64); err == nil {
+			feature = appendVarintField(feature, mvtFeatureID, id)
+		}
+		// the keys are id and name, in this order
+		feature = appendField(feature, mvtFeatureTags, appendVarints(nil, 0, value(g.ID), 1, value(name)))
+		feature = appendVarintField(feature, mvtFeatureType, mvtPolygon)
+		feature = appendField(feature, mvtFeatureGeom, appendVarints(nil, geometry...))
+		features = appendField(features, mvtLayerFeatures, feature)
+	}
+	if len(features) == 0 {
+		return nil
+	}
+
+	encoded := appendVarintField(nil, mvtLayerVersion, 2)
+	encoded = appendField(encoded, mvtLayerName, []byte(layer))
+	encoded = append(encoded, features...)
+	encoded = appendField(encoded, mvtLayerKeys, []byte("id"))
+	encoded = appendField(encoded, mvtLayerKeys, []byte("name"))
+	for _, v := range values {
+		encoded = appendField(encoded, mvtLayerValues, appendField(nil, mvtValueString, []byte(v)))
+	}
+	return appendVarintField(encoded, mvtLayerExtent, TileExtent)
+}
+
+//tileProjection projects the points in the coordinates of the Web Mercator tile z/x/y
+type tileProjection struct {
+	//n is the number of tiles on each side at the zoom
+	n, x, y float64
+	//bbox is the tile with its buffer in longitude and latitude: west, south, east, north
+	bbox [4]float64
+}
+
+func newTileProjection(z, x, y int) tileProjection {
+	n := math.Exp2(float64(z))
+	buffer := float64(tileBuffer) / TileExtent
+	lng := func(x float64) float64 { return x/n*360 - 180 }
+	lat := func(y float64) float64 { return deg(math.Atan(math.Sinh(math.Pi * (1 - 2*y/n)))) }
+	return tileProjection{
+		n: n, x: float64(x), y: float64(y),
+		bbox: [4]float64{
+			lng(float64(x) - buffer), lat(float64(y+1) + buffer),
+			lng(float64(x+1) + buffer), lat(float64(y) - buffer),
+		},
+	}
+}
+
+//box returns the tile with its buffer as a bounding box, with the latitudes on the X axis
+func (p tileProjection) box() shp.Box {
+	return shp.Box{MinX: p.bbox[1], MinY: p.bbox[0], MaxX: p.bbox[3], MaxY: p.bbox[2]}
+}
+
+//project returns the point in the coordinates of the tile
+func (p tileProjection) project(point Point) [2]float64 {
+	lat := rad(math.Max(-maxLatitude, math.Min(maxLatitude, point.Lat)))
+	x := (point.Lng + 180) / 360 * p.n
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * p.n
+	return [2]float64{(x - p.x) * TileExtent, (y - p.y) * TileExtent}
+}
+
+//simplify returns the arc without the points closer than a point of the tile at its latitude
+func (p tileProjection) simplify(arc Ring) Ring {
+	if len(arc) == 0 {
+		return arc
+	}
+	circumference := 2 * math.Pi * earthRadius * 1000
+	tolerance := circumference * math.Cos(rad(arc[0].Lat)) / (p.n * TileExtent)
+	return simplifyLine(arc, arc, tolerance, DouglasPeucker)
+}
+
+//geometry returns the commands of the polygons made by the rings of arcs, clipped to the tile.
+//It is nil if no polygon is left in the tile.
+func (p tileProjection) geometry(polygons [][][]int, arcs []Ring) []uint64 {
+	commands := make([]uint64, 0)
+	var cursor [2]int
+	for _, polygon := range arcsMultiPolygon(polygons, arcs) {
+		for i, ring := range polygon {
+			points := p.clip(ring)
+			if points == nil {
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			// the outer rings are clockwise on the screen, with a positive area, the holes are counterclockwise
+			if outer := i == 0; (tileArea(points) > 0) != outer {
+				for a, b := 0, len(points)-1; a < b; a, b = a+1, b-1 {
+					points[a], points[b] = points[b], points[a]
+				}
+			}
+
+			commands = append(commands, mvtCommand(mvtMoveTo, 1))
+			commands = append(commands, zigzag(points[0][0]-cursor[0]), zigzag(points[0][1]-cursor[1]))
+			commands = append(commands, mvtCommand(mvtLineTo, len(points)-1))
+			for j := 1; j < len(points); j++ {
+				commands = append(commands, zigzag(points[j][0]-points[j-1][0]), zigzag(points[j][1]-points[j-1][1]))
+			}
+			commands = append(commands, mvtCommand(mvtClosePath, 1))
+			cursor = points[len(points)-1]
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	return commands
+}
+
+//clip returns the open ring projected in the integer coordinates of the tile and clipped to its buffer,
+//with the Sutherland-Hodgman algorithm. It is nil if the ring collapses.
+func (p tileProjection) clip(ring Ring) [][2]int {
+	points := make([][2]float64, 0, len(ring))
+	for _, point := range ring {
+		points = append(points, p.project(point))
+	}
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+
+	low, high := float64(-tileBuffer), float64(TileExtent+tileBuffer)
+	for edge := 0; edge < 4 && len(points) > 0; edge++ {
+		axis := edge % 2
+		bound := low
+		inside := func(q [2]float64) bool { return q[axis] >= low }
+		if edge >= 2 {
+			bound = high
+			inside = func(q [2]float64) bool { return q[axis] <= high }
+		}
+
+		clipped := make([][2]float64, 0, len(points))
+		prev := points[len(points)-1]
+		for _, q := range points {
+			if inside(q) != inside(prev) {
+				f := (bound - prev[axis]) / (q[axis] - prev[axis])
+				crossing := [2]float64{prev[0] + f*(q[0]-prev[0]), prev[1] + f*(q[1]-prev[1])}
+				crossing[axis] = bound
+				clipped = append(clipped, crossing)
+			}
+			if inside(q) {
+				clipped = append(clipped, q)
+			}
+			prev = q
+		}
+		points = clipped
+	}
+
+	rounded := make([][2]int, 0, len(points))
+	for _, q := range points {
+		r := [2]int{int(math.Round(q[0])), int(math.Round(q[1]))}
+		if len(rounded) == 0 || rounded[len(rounded)-1] != r {
+			rounded = append(rounded, r)
+		}
+	}
+	for len(rounded) > 1 && rounded[0] == rounded[len(rounded)-1] {
+		rounded = rounded[:len(rounded)-1]
+	}
+	if len(rounded) < 3 || tileArea(rounded) == 0 {
+		return nil
+	}
+	return rounded
+}
+
+//tileArea returns twice the signed area of the open ring, positive if clockwise on the screen
+func tileArea(ring [][2]int) int {
+	area := 0
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	return area
+}
+
+//mvtCommand returns the integer of a geometry command repeated count times
+func mvtCommand(id, count int) uint64 {
+	return uint64(id&0x7) | uint64(count)<<3
+}
+
+//zigzag encodes a signed integer as an unsigned one, with the small absolute values first
+func zigzag(n int) uint64 {
+	return uint64((int64(n) << 1) ^ (int64(n) >> 63))
+}
+
+//appendVarint appends the protocol buffers varint of v
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+//appendVarints appends the packed varints
+func appendVarints(b []byte, values ...uint64) []byte {
+	for _, v := range values {
+		b = appendVarint(b, v)
+	}
+	return b
+}
+
+//appendVarintField appends the varint field with the number
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return appendVarint(appendVarint(b, uint64(field)<<3), v)
+}
+
+//appendField appends the length delimited field with the number, like a message, a string or packed varints
+func appendField(b []byte, field int, data []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,172 @@
+package gomuni
+
+import (
+	"testing"
+)
+
+//protoFields decodes the fields of a protocol buffers message, the varints and the length delimited ones
+func protoFields(t *testing.T, b []byte) [][2]interface{} {
+	varint := func() uint64 {
+		var v uint64
+		for shift := uint(0); ; shift += 7 {
+			if len(b) == 0 {
+				t.Fatal("truncated varint")
+			}
+			c := b[0]
+			b = b[1:]
+			v |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				return v
+			}
+		}
+	}
+
+	fields := make([][2]interface{}, 0)
+	for len(b) > 0 {
+		key := varint()
+		switch key & 0x7 {
+		case 0:
+			fields = append(fields, [2]interface{}{int(key >> 3), varint()})
+		case 2:
+			n := int(varint())
+			if n > len(b) {
+				t.Fatalf("truncated field %d", key>>3)
+			}
+			fields = append(fields, [2]interface{}{int(key >> 3), b[:n]})
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&0x7)
+		}
+	}
+	return fields
+}
+
+//tileLayers decodes the layers of a Mapbox Vector Tile, with the names and the geometries of their features by ID
+func tileLayers(t *testing.T, tile []byte) map[string]map[string][]uint64 {
+	layers := make(map[string]map[string][]uint64)
+	for _, layer := range protoFields(t, tile) {
+		var name string
+		var keys, values []string
+		features := make([][]byte, 0)
+		for _, f := range protoFields(t, layer[1].([]byte)) {
+			switch f[0] {
+			case mvtLayerName:
+				name = string(f[1].([]byte))
+			case mvtLayerFeatures:
+				features = append(features, f[1].([]byte))
+			case mvtLayerKeys:
+				keys = append(keys, string(f[1].([]byte)))
+			case mvtLayerValues:
+				values = append(values, string(protoFields(t, f[1].([]byte))[0][1].([]byte)))
+			case mvtLayerExtent:
+				if f[1].(uint64) != TileExtent {
+					t.Errorf("extent %d", f[1])
+				}
+			}
+		}
+
+		layers[name] = make(map[string][]uint64)
+		for _, feature := range features {
+			properties := make(map[string]string)
+			var geometry []uint64
+			for _, f := range protoFields(t, feature) {
+				var packed []uint64
+				if data, ok := f[1].([]byte); ok {
+					packed = decodePacked(t, data)
+				}
+				switch f[0] {
+				case mvtFeatureTags:
+					for i := 0; i+1 < len(packed); i += 2 {
+						properties[keys[packed[i]]] = values[packed[i+1]]
+					}
+				case mvtFeatureGeom:
+					geometry = packed
+				}
+			}
+			if properties["name"] == "" {
+				t.Errorf("layer %s: feature %s without name", name, properties["id"])
+			}
+			layers[name][properties["id"]] = geometry
+		}
+	}
+	return layers
+}
+
+//decodePacked decodes packed varints
+func decodePacked(t *testing.T, b []byte) []uint64 {
+	values := make([]uint64, 0)
+	var v uint64
+	var shift uint
+	for _, c := range b {
+		v |= uint64(c&0x7f) << shift
+		shift += 7
+		if c < 0x80 {
+			values = append(values, v)
+			v, shift = 0, 0
+		}
+	}
+	if shift != 0 {
+		t.Fatal("truncated packed varints")
+	}
+	return values
+}
+
+//tilePoints returns the points of the geometry commands
+func tilePoints(t *testing.T, geometry []uint64) [][2]int {
+	points := make([][2]int, 0)
+	var x, y int
+	unzigzag := func(v uint64) int { return int(v>>1) ^ -int(v&1) }
+	for i := 0; i < len(geometry); {
+		id, count := int(geometry[i]&0x7), int(geometry[i]>>3)
+		i++
+		switch id {
+		case mvtMoveTo, mvtLineTo:
+			for j := 0; j < count; j++ {
+				x, y = x+unzigzag(geometry[i]), y+unzigzag(geometry[i+1])
+				points = append(points, [2]int{x, y})
+				i += 2
+			}
+		case mvtClosePath:
+		default:
+			t.Fatalf("unexpected command %d", id)
+		}
+	}
+	return points
+}
+
+func Test_MarshalMVT(t *testing.T) {
+	topology := newTestCountry().Topology(TopologyOptions{})
+
+	layers := tileLayers(t, topology.MarshalMVT(0, 0, 0))
+	if len(layers[TopologyRegions]) != 2 || len(layers[TopologyCities]) != 2 || len(layers[TopologyTowns]) != 3 {
+		t.Fatalf("unexpected layers %v", layers)
+	}
+	// Italy is in the middle of the world, in the north-east quarter
+	for _, p := range tilePoints(t, layers[TopologyRegions]["1"]) {
+		if p[0] < TileExtent/2 || p[0] > TileExtent*3/4 || p[1] < TileExtent/4 || p[1] > TileExtent/2 {
+			t.Errorf("the point %v of Piemonte is out of place", p)
+		}
+	}
+
+	// the tile 10/533/368 is inside Torino, clipped to the tile with its buffer
+	layers = tileLayers(t, topology.MarshalMVT(10, 533, 368))
+	if _, ok := layers[TopologyTowns]["001272"]; !ok || len(layers[TopologyTowns]) != 1 {
+		t.Fatalf("unexpected towns %v", layers[TopologyTowns])
+	}
+	torino := tilePoints(t, layers[TopologyTowns]["001272"])
+	if tileArea(torino) <= 0 {
+		t.Errorf("the outer ring %v is not clockwise", torino)
+	}
+	for _, p := range torino {
+		if p[0] < -tileBuffer || p[0] > TileExtent+tileBuffer || p[1] < -tileBuffer || p[1] > TileExtent+tileBuffer {
+			t.Errorf("the point %v is not clipped", p)
+		}
+	}
+
+	if tile := topology.MarshalMVT(4, 0, 0); len(tile) != 0 {
+		t.Errorf("got %d bytes in an empty tile", len(tile))
+	}
+	if tile := (&Topology{}).MarshalMVT(0, 0, 0); tile != nil {
+		t.Errorf("got %d bytes from a decoded Topology", len(tile))
+	}
+}
@@ -0,0 +1,272 @@
+package gomuni
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//Match is a unit found by name, with its Region, City and Town.
+//The Level is "region", "city" or "town", and the units below it are nil.
+type Match struct {
+	Location
+	Level string
+	Name  string
+	Score float64
+}
+
+//nameEntry is a name of a unit in the nameIndex
+type nameEntry struct {
+	key      string
+	tokens   []string
+	name     string
+	level    string
+	location Location
+}
+
+//nameIndex indexes the names of all the units, normalized with normalizeName
+type nameIndex struct {
+	entries []nameEntry
+}
+
+func (idx *nameIndex) add(name, level string, location Location) {
+	key := normalizeName(name)
+	if key == "" {
+		return
+	}
+	idx.entries = append(idx.entries, nameEntry{
+		key:      key,
+		tokens:   strings.Fields(key),
+		name:     name,
+		level:    level,
+		location: location,
+	})
+}
+
+//addAll adds the name of a unit and all its localized Names
+func (idx *nameIndex) addAll(name string, names Names, level string, location Location) {
+	idx.add(name, level, location)
+	for _, n := range names {
+		if n != name {
+			idx.add(n, level, location)
+		}
+	}
+}
+
+//SearchByName returns the units with the name matching the query, sorted by Score.
+//The names are compared ignoring the case, the accents, the apostrophes, the prepositions
+//and the abbreviations of the saints (S., San, Santo, Sant'), so that "reggio nell'emilia" finds
+//"Reggio Emilia" and "S. Agata" finds "Sant'Agata". A query can be the prefix of a name, for the autocomplete,
+//and it can have some typos. Every localized name is matched, like "Bozen" for Bolzano/Bozen.
+//The exact matches score 1, the others less.
+//If limit is positive no more than limit matches are returned.
+func (c *Country) SearchByName(query string, limit int) []Match {
+	q := normalizeName(query)
+	if q == "" {
+		return []Match{}
+	}
+	qTokens := strings.Fields(q)
+
+	best := make(map[Location]int)
+	matches := make([]Match, 0)
+	for _, e := range c.names.entries {
+		score := matchScore(q, qTokens, e)
+		if score == 0 {
+			continue
+		}
+
+		// a unit with more names is returned once, with the best one
+		if i, found := best[e.location]; found {
+			if score > matches[i].Score {
+				matches[i].Name, matches[i].Score = e.name, score
+			}
+			continue
+		}
+		best[e.location] = len(matches)
+		matches = append(matches, Match{Location: e.location, Level: e.level, Name: e.name, Score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Name) != len(matches[j].Name) {
+			return len(matches[i].Name) < len(matches[j].Name)
+		}
+		return matches[i].Name < matches[j].Name
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+//matchScore returns how much the normalized query matches the entry, from 0 (no match) to 1 (same name)
+func matchScore(q string, qTokens []string, e nameEntry) float64 {
+	if q == e.key {
+		return 1
+	}
+
+	qLen, keyLen := len([]rune(q)), len([]rune(e.key))
+	if strings.HasPrefix(e.key, q) {
+		return 0.8 + 0.15*float64(qLen)/float64(keyLen)
+	}
+	if tokensPrefix(qTokens, e.tokens) {
+		return 0.6 + 0.15*float64(qLen)/float64(keyLen)
+	}
+
+	typos := maxTypos(qLen)
+	if typos == 0 {
+		return 0
+	}
+	if abs(qLen-keyLen) <= typos {
+		if d := editDistance(q, e.key); d <= typos {
+			return 0.4 + 0.35*(1-float64(d)/float64(maxInt(qLen, keyLen)))
+		}
+	}
+	if qLen < keyLen {
+		if d := editDistance(q, string([]rune(e.key)[:qLen])); d <= typos {
+			return 0.3 + 0.25*(1-float64(d)/float64(qLen))
+		}
+	}
+	return 0
+}
+
+//tokensPrefix checks if every token of the query is the prefix of a different token of the name
+func tokensPrefix(qTokens, tokens []string) bool {
+	if len(qTokens) > len(tokens) {
+		return false
+	}
+	used := make([]bool, len(tokens))
+	for _, q := range qTokens {
+		found := false
+		for i, t := range tokens {
+			if !used[i] && strings.HasPrefix(t, q) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//maxTypos returns the typos tolerated in a query of n letters
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+//editDistance returns the Damerau-Levenshtein distance (with adjacent transpositions) between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//nameStopwords are the articles and prepositions ignored in the names, like in "Reggio nell'Emilia"
+var nameStopwords = map[string]bool{
+	"d": true, "di": true, "de": true, "del": true, "dell": true, "della": true, "dello": true, "dei": true, "degli": true, "delle": true,
+	"l": true, "la": true, "il": true, "lo": true, "le": true, "i": true, "gli": true,
+	"in": true, "nel": true, "nell": true, "nella": true, "nello": true, "nei": true,
+	"al": true, "all": true, "alla": true, "allo": true, "ai": true, "sul": true, "sull": true, "sulla": true,
+	"e": true, "ed": true,
+}
+
+//nameAbbreviations are the forms of the saints, normalized to the same token
+var nameAbbreviations = map[string]string{
+	"s": "san", "sa": "san", "sant": "san", "santa": "san", "santo": "san",
+	"ss": "santi", "sti": "santi", "ste": "sante",
+}
+
+//nameFolding are the letters with diacritics folded to their base letters
+var nameFolding = map[rune]string{}
+
+func init() {
+	for base, letters := range map[string]string{
+		"a": "àáâãäåāăą", "c": "çćčĉ", "d": "ďđ", "e": "èéêëēĕėęě", "g": "ĝğġģ", "i": "ìíîïĩīĭį",
+		"l": "ĺļľł", "n": "ñńņň", "o": "òóôõöøōŏő", "r": "ŕŗř", "s": "śŝşš", "t": "ţťŧ",
+		"u": "ùúûüũūŭůűų", "y": "ýÿŷ", "z": "źżž", "ss": "ß", "ae": "æ", "oe": "œ",
+	} {
+		for _, r := range letters {
+			nameFolding[r] = base
+		}
+	}
+}
+
+//normalizeName lowercases the name, folds the accents, replaces the punctuation with spaces,
+//drops the articles and the prepositions and expands the abbreviations
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case nameFolding[r] != "":
+			b.WriteString(nameFolding[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	tokens := make([]string, 0)
+	for _, t := range strings.Fields(b.String()) {
+		if nameStopwords[t] {
+			continue
+		}
+		if expanded, ok := nameAbbreviations[t]; ok {
+			t = expanded
+		}
+		tokens = append(tokens, t)
+	}
+	return strings.Join(tokens, " ")
+}
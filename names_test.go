@@ -0,0 +1,90 @@
+package gomuni
+
+import (
+	"testing"
+)
+
+func Test_normalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Reggio nell'Emilia", "reggio emilia"},
+		{"Reggio Emilia", "reggio emilia"},
+		{"Sant'Agata di Militello", "san agata militello"},
+		{"S. Agata di Militello", "san agata militello"},
+		{"Santo Stefano", "san stefano"},
+		{"Forlì-Cesena", "forli cesena"},
+		{"SS. Cosma e Damiano", "santi cosma damiano"},
+		{"Doberdò del Lago/Doberdob", "doberdo lago doberdob"},
+	}
+	for _, tt := range tests {
+		if got := normalizeName(tt.name); got != tt.want {
+			t.Errorf("normalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"torino", "torino", 0},
+		{"torino", "tornio", 1},
+		{"torino", "tolino", 1},
+		{"torino", "trino", 1},
+		{"rimini", "rmini", 1},
+		{"moncalieri", "moncaleiri", 1},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func Test_SearchByName(t *testing.T) {
+	country := newTestCountry()
+
+	tests := []struct {
+		query string
+		level string
+		name  string
+		exact bool
+	}{
+		{"Moncalieri", levelTown, "Moncalieri", true},
+		{"moncalièri", levelTown, "Moncalieri", true},
+		{"monc", levelTown, "Moncalieri", false},
+		{"Moncaleiri", levelTown, "Moncalieri", false},
+		{"emilia", levelRegion, "Emilia-Romagna", false},
+		{"romagna emilia", levelRegion, "Emilia-Romagna", false},
+	}
+	for _, tt := range tests {
+		matches := country.SearchByName(tt.query, 1)
+		if len(matches) == 0 {
+			t.Errorf("SearchByName(%q) found nothing", tt.query)
+			continue
+		}
+		m := matches[0]
+		if m.Level != tt.level || m.Name != tt.name || (m.Score == 1) != tt.exact {
+			t.Errorf("SearchByName(%q) = %s %q score %v, want %s %q", tt.query, m.Level, m.Name, m.Score, tt.level, tt.name)
+		}
+	}
+
+	// the exact matches come first, then the hierarchy of a town is complete
+	matches := country.SearchByName("Torino", 0)
+	if len(matches) != 2 || matches[0].Score != 1 || matches[1].Score != 1 {
+		t.Fatalf("SearchByName(Torino) = %+v, want the city and the town", matches)
+	}
+	for _, m := range matches {
+		if m.Level == levelTown && (m.Region == nil || m.City == nil || m.Town == nil) {
+			t.Errorf("SearchByName(Torino) town without its hierarchy: %+v", m)
+		}
+	}
+
+	if matches := country.SearchByName("xyz", 10); len(matches) != 0 {
+		t.Errorf("SearchByName(xyz) = %+v, want no matches", matches)
+	}
+}
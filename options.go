@@ -0,0 +1,43 @@
+package gomuni
+
+//Logger is used by the loader to report its progress.
+//It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+//Options configures LoadWithOptions
+type Options struct {
+	RegionFolder string
+	CityFolder   string
+	TownFolder   string
+
+	//Projection is used for the shapefiles without a .prj file.
+	//If nil the coordinates are read as WGS84 UTM zone 32N, as in the ISTAT 2016 release.
+	Projection Projection
+
+	//Vintage is the schema of the DBF fields.
+	//If nil it is detected from the header of each layer.
+	Vintage *Vintage
+
+	//Logger receives the loading messages. If nil nothing is logged.
+	Logger Logger
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+func (o Options) logger() Logger {
+	if o.Logger == nil {
+		return nopLogger{}
+	}
+	return o.Logger
+}
+
+func (o Options) projection() Projection {
+	if o.Projection == nil {
+		return UTM(32, true)
+	}
+	return o.Projection
+}
@@ -0,0 +1,287 @@
+package gomuni
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//wktNode is a keyword of a WKT string, with its arguments.
+//Each argument is a string, a float64 or a *wktNode.
+type wktNode struct {
+	keyword string
+	args    []interface{}
+}
+
+//child returns the first child node with the provided keyword
+func (n *wktNode) child(keyword string) *wktNode {
+	for _, arg := range n.args {
+		if c, ok := arg.(*wktNode); ok && strings.EqualFold(c.keyword, keyword) {
+			return c
+		}
+	}
+	return nil
+}
+
+//name returns the first string argument of the node
+func (n *wktNode) name() string {
+	if n == nil || len(n.args) == 0 {
+		return ""
+	}
+	s, _ := n.args[0].(string)
+	return s
+}
+
+//number returns the i-th argument of the node as a float64
+func (n *wktNode) number(i int) (float64, bool) {
+	if n == nil || i >= len(n.args) {
+		return 0, false
+	}
+	f, ok := n.args[i].(float64)
+	return f, ok
+}
+
+//parameters returns the PARAMETER children of a PROJCS, keyed by their normalized name
+func (n *wktNode) parameters() map[string]float64 {
+	params := make(map[string]float64)
+	for _, arg := range n.args {
+		if c, ok := arg.(*wktNode); ok && strings.EqualFold(c.keyword, "PARAMETER") {
+			if v, ok := c.number(1); ok {
+				params[normalizeWKTName(c.name())] = v
+			}
+		}
+	}
+	return params
+}
+
+type wktParser struct {
+	input string
+	pos   int
+}
+
+//parseWKT parses the Well Known Text of a coordinate reference system,
+//as found in the .prj file of a shapefile
+func parseWKT(input string) (*wktNode, error) {
+	p := &wktParser{input: input}
+	node, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.input[p.pos], p.pos)
+	}
+	return node, nil
+}
+
+func (p *wktParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *wktParser) node() (*wktNode, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, fmt.Errorf("expected keyword at offset %d", p.pos)
+	}
+	node := &wktNode{keyword: p.input[start:p.pos]}
+
+	p.skipSpaces()
+	if p.pos >= len(p.input) || (p.input[p.pos] != '[' && p.input[p.pos] != '(') {
+		// keywords without arguments, like the axis directions
+		return node, nil
+	}
+	closing := byte(']')
+	if p.input[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unterminated %s", node.keyword)
+		}
+
+		switch c := p.input[p.pos]; {
+		case c == closing:
+			p.pos++
+			return node, nil
+		case c == ',':
+			p.pos++
+		case c == '"':
+			end := strings.IndexByte(p.input[p.pos+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", p.pos)
+			}
+			node.args = append(node.args, p.input[p.pos+1:p.pos+1+end])
+			p.pos += end + 2
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			start := p.pos
+			for p.pos < len(p.input) && strings.IndexByte("+-.0123456789eE", p.input[p.pos]) >= 0 {
+				p.pos++
+			}
+			f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number at offset %d: %v", start, err)
+			}
+			node.args = append(node.args, f)
+		default:
+			child, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			node.args = append(node.args, child)
+		}
+	}
+}
+
+//normalizeWKTName lowercases the name and removes the separators, so that
+//"Transverse_Mercator" and "Transverse Mercator" are the same
+func normalizeWKTName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var ellipsoidsByName = map[string]Ellipsoid{
+	"wgs84":             WGS84Ellipsoid,
+	"wgs1984":           WGS84Ellipsoid,
+	"grs1980":           GRS80Ellipsoid,
+	"grs80":             GRS80Ellipsoid,
+	"international1924": International1924Ellipsoid,
+	"international1909": International1924Ellipsoid,
+	"hayford1909":       International1924Ellipsoid,
+	"bessel1841":        Bessel1841Ellipsoid,
+}
+
+var datumsByName = map[string]Datum{
+	"wgs1984":                                WGS84Datum,
+	"dwgs1984":                               WGS84Datum,
+	"worldgeodeticsystem1984":                WGS84Datum,
+	"etrs1989":                               ETRS89Datum,
+	"detrs1989":                              ETRS89Datum,
+	"europeanterrestrialreferencesystem1989": ETRS89Datum,
+	"etrf2000":                               ETRS89Datum,
+	"detrf2000":                              ETRS89Datum,
+	"rdn2008":                                ETRS89Datum,
+	"drdn2008":                               ETRS89Datum,
+	"igm95":                                  ETRS89Datum,
+	"digm95":                                 ETRS89Datum,
+	"italiangeodeticnetwork2008":             ETRS89Datum,
+	"montemario":                             Roma40Datum,
+	"dmontemario":                            Roma40Datum,
+	"roma1940":                               Roma40Datum,
+	"droma1940":                              Roma40Datum,
+	"montemariorome":                         Roma40Datum,
+	"europeandatum1950":                      ED50Datum,
+	"deuropean1950":                          ED50Datum,
+	"ed50":                                   ED50Datum,
+}
+
+//ParsePRJ returns the Projection described by the WKT content of a .prj file.
+//It supports geographic coordinates and the transverse mercator projections (UTM and Gauss-Boaga)
+//over the WGS84, ETRS89, Roma40 and ED50 datums.
+func ParsePRJ(wkt string) (Projection, error) {
+	root, err := parseWKT(strings.TrimSpace(wkt))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedCRS, err)
+	}
+
+	switch strings.ToUpper(root.keyword) {
+	case "GEOGCS":
+		datum, primeMeridian, err := parseGeogcs(root)
+		if err != nil {
+			return nil, err
+		}
+		g := Geographic{Datum: datum, PrimeMeridian: primeMeridian}
+		if unit, ok := root.child("UNIT").number(1); ok {
+			g.Unit = deg(unit)
+		}
+		return g, nil
+
+	case "PROJCS":
+		geogcs := root.child("GEOGCS")
+		if geogcs == nil {
+			return nil, fmt.Errorf("%w: %s has no GEOGCS", ErrUnsupportedCRS, root.name())
+		}
+		datum, primeMeridian, err := parseGeogcs(geogcs)
+		if err != nil {
+			return nil, err
+		}
+
+		projection := normalizeWKTName(root.child("PROJECTION").name())
+		switch projection {
+		case "transversemercator", "gausskruger", "gaussboaga":
+		default:
+			return nil, fmt.Errorf("%w: projection %q of %s", ErrUnsupportedCRS, root.child("PROJECTION").name(), root.name())
+		}
+
+		params := root.parameters()
+		tm := TransverseMercator{
+			Datum:            datum,
+			CentralMeridian:  params["centralmeridian"] + primeMeridian,
+			LatitudeOfOrigin: params["latitudeoforigin"],
+			ScaleFactor:      1,
+			FalseEasting:     params["falseeasting"],
+			FalseNorthing:    params["falsenorthing"],
+		}
+		if scale, ok := params["scalefactor"]; ok {
+			tm.ScaleFactor = scale
+		}
+		if unit, ok := root.child("UNIT").number(1); ok {
+			tm.Unit = unit
+			// the false origin is expressed in the linear unit of the projection
+			tm.FalseEasting *= unit
+			tm.FalseNorthing *= unit
+		}
+		return tm, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedCRS, root.keyword)
+}
+
+//parseGeogcs returns the datum and the prime meridian of a GEOGCS node
+func parseGeogcs(geogcs *wktNode) (Datum, float64, error) {
+	datumNode := geogcs.child("DATUM")
+	if datumNode == nil {
+		return Datum{}, 0, fmt.Errorf("%w: %s has no DATUM", ErrUnsupportedCRS, geogcs.name())
+	}
+
+	datum, known := datumsByName[normalizeWKTName(datumNode.name())]
+
+	if spheroid := datumNode.child("SPHEROID"); spheroid != nil {
+		a, okA := spheroid.number(1)
+		invF, okF := spheroid.number(2)
+		if okA && okF {
+			datum.Ellipsoid = Ellipsoid{A: a, InvF: invF}
+		} else if e, ok := ellipsoidsByName[normalizeWKTName(spheroid.name())]; ok {
+			datum.Ellipsoid = e
+		}
+	}
+
+	if towgs84 := datumNode.child("TOWGS84"); towgs84 != nil {
+		for i := range datum.ToWGS84 {
+			datum.ToWGS84[i], _ = towgs84.number(i)
+		}
+		known = true
+	}
+
+	if !known {
+		return Datum{}, 0, fmt.Errorf("%w: datum %q", ErrUnsupportedCRS, datumNode.name())
+	}
+	datum.Name = datumNode.name()
+
+	primeMeridian, _ := geogcs.child("PRIMEM").number(1)
+	return datum, primeMeridian, nil
+}
@@ -0,0 +1,72 @@
+package gomuni
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func Test_ParsePRJ(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+		x, y float64
+		want Point
+	}{
+		{
+			"ISTAT WGS84 UTM 32N",
+			`PROJCS["WGS_1984_UTM_Zone_32N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",9.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`,
+			500000, 5000000,
+			Point{45.153477, 9},
+		},
+		{
+			"ETRS89 UTM 33N",
+			`PROJCS["ETRS89 / UTM zone 33N",GEOGCS["ETRS89",DATUM["European_Terrestrial_Reference_System_1989",SPHEROID["GRS 1980",6378137,298.257222101,AUTHORITY["EPSG","7019"]],TOWGS84[0,0,0,0,0,0,0]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",15],PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",0],UNIT["metre",1],AXIS["Easting",EAST],AXIS["Northing",NORTH]]`,
+			291000, 4641000,
+			Point{41.893310, 12.480676},
+		},
+		{
+			"WGS84 geographic",
+			`GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			12.4964, 41.9028,
+			Point{41.9028, 12.4964},
+		},
+		{
+			"Monte Mario Gauss-Boaga Ovest",
+			`PROJCS["Monte_Mario_Italy_1",GEOGCS["GCS_Monte_Mario",DATUM["D_Monte_Mario",SPHEROID["International_1924",6378388.0,297.0]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",1500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",9.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`,
+			1514000, 5034000,
+			Point{45.459206, 9.178709},
+		},
+	}
+
+	for _, tt := range tests {
+		projection, err := ParsePRJ(tt.wkt)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		got, err := projection.ToLatLon(tt.x, tt.y)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got.Lat-tt.want.Lat) > 1e-5 || math.Abs(got.Lng-tt.want.Lng) > 1e-5 {
+			t.Errorf("%s: ToLatLon(%f, %f) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func Test_ParsePRJUnsupported(t *testing.T) {
+	wkts := []string{
+		`PROJCS["ETRS89 / LAEA Europe",GEOGCS["ETRS89",DATUM["European_Terrestrial_Reference_System_1989",SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Lambert_Azimuthal_Equal_Area"],PARAMETER["latitude_of_center",52],PARAMETER["longitude_of_center",10],PARAMETER["false_easting",4321000],PARAMETER["false_northing",3210000],UNIT["metre",1]]`,
+		`GEOGCS["Unknown",DATUM["D_Unknown",SPHEROID["Sphere",6371000,0]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+		`PROJCS["broken"`,
+	}
+
+	for _, wkt := range wkts {
+		if _, err := ParsePRJ(wkt); !errors.Is(err, ErrUnsupportedCRS) {
+			t.Errorf("expected ErrUnsupportedCRS for %s, got %v", wkt, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package gomuni
+
+import (
+	"fmt"
+	"math"
+)
+
+//Projection converts the coordinates of a shapefile into a WGS84 geolocation Point
+type Projection interface {
+	ToLatLon(x, y float64) (Point, error)
+}
+
+//Ellipsoid is the reference ellipsoid of a datum, defined by its semi-major axis and inverse flattening
+type Ellipsoid struct {
+	A    float64
+	InvF float64
+}
+
+//Reference ellipsoids used by the common italian datums
+var (
+	WGS84Ellipsoid             = Ellipsoid{A: 6378137, InvF: 298.257223563}
+	GRS80Ellipsoid             = Ellipsoid{A: 6378137, InvF: 298.257222101}
+	International1924Ellipsoid = Ellipsoid{A: 6378388, InvF: 297}
+	Bessel1841Ellipsoid        = Ellipsoid{A: 6377397.155, InvF: 299.1528128}
+)
+
+//eccentricity2 returns the squared first eccentricity of the Ellipsoid
+func (e Ellipsoid) eccentricity2() float64 {
+	f := 1 / e.InvF
+	return f * (2 - f)
+}
+
+//Datum is a geodetic datum, with the 7 parameters (position vector convention) of its transformation to WGS84.
+//The translations are in metres, the rotations in arcseconds and the scale in parts per million.
+type Datum struct {
+	Name      string
+	Ellipsoid Ellipsoid
+	ToWGS84   [7]float64
+}
+
+//Datums used by the italian cartography
+var (
+	WGS84Datum  = Datum{Name: "WGS84", Ellipsoid: WGS84Ellipsoid}
+	ETRS89Datum = Datum{Name: "ETRS89", Ellipsoid: GRS80Ellipsoid}
+	Roma40Datum = Datum{Name: "Roma40", Ellipsoid: International1924Ellipsoid, ToWGS84: [7]float64{-104.1, -49.1, -9.9, 0.971, -2.917, 0.714, -11.68}}
+	ED50Datum   = Datum{Name: "ED50", Ellipsoid: International1924Ellipsoid, ToWGS84: [7]float64{-87, -98, -121, 0, 0, 0, 0}}
+)
+
+//isWGS84 returns true if the Datum does not need a transformation
+func (d Datum) isWGS84() bool {
+	return d.ToWGS84 == [7]float64{} &&
+		d.Ellipsoid.A == WGS84Ellipsoid.A && math.Abs(d.Ellipsoid.InvF-WGS84Ellipsoid.InvF) < 1e-6
+}
+
+//toWGS84 converts the geodetic coordinates (in degrees) from the Datum to WGS84
+func (d Datum) toWGS84(lat, lng float64) (float64, float64) {
+	if d.isWGS84() {
+		return lat, lng
+	}
+
+	x, y, z := toGeocentric(d.Ellipsoid, rad(lat), rad(lng))
+
+	t := d.ToWGS84
+	rx, ry, rz := rad(t[3]/3600), rad(t[4]/3600), rad(t[5]/3600)
+	s := 1 + t[6]/1e6
+
+	x, y, z = t[0]+s*(x-rz*y+ry*z),
+		t[1]+s*(rz*x+y-rx*z),
+		t[2]+s*(-ry*x+rx*y+z)
+
+	phi, lambda := fromGeocentric(WGS84Ellipsoid, x, y, z)
+	return deg(phi), deg(lambda)
+}
+
+//Geographic is a Projection for the shapefiles having longitude and latitude as coordinates
+type Geographic struct {
+	Datum Datum
+	//PrimeMeridian is the longitude of the prime meridian from Greenwich, in degrees
+	PrimeMeridian float64
+	//Unit converts the coordinates to degrees
+	Unit float64
+}
+
+//ToLatLon implements the Projection interface
+func (g Geographic) ToLatLon(x, y float64) (Point, error) {
+	unit := g.Unit
+	if unit == 0 {
+		unit = 1
+	}
+
+	lat := y * unit
+	lng := x*unit + g.PrimeMeridian
+	if lat < -90 || lat > 90 {
+		return Point{}, fmt.Errorf("latitude %f out of range", lat)
+	}
+
+	lat, lng = g.Datum.toWGS84(lat, lng)
+	return Point{lat, lng}, nil
+}
+
+//TransverseMercator is a Projection for the UTM and Gauss-Boaga coordinates
+type TransverseMercator struct {
+	Datum Datum
+	//CentralMeridian and LatitudeOfOrigin are in degrees from Greenwich
+	CentralMeridian  float64
+	LatitudeOfOrigin float64
+	ScaleFactor      float64
+	FalseEasting     float64
+	FalseNorthing    float64
+	//Unit converts the coordinates to metres
+	Unit float64
+}
+
+//UTM returns the TransverseMercator Projection of a WGS84 UTM zone
+func UTM(zoneNumber int, north bool) TransverseMercator {
+	tm := TransverseMercator{
+		Datum:           WGS84Datum,
+		CentralMeridian: float64(zoneNumberToCentralLongitude(zoneNumber)),
+		ScaleFactor:     k0,
+		FalseEasting:    500000,
+	}
+	if !north {
+		tm.FalseNorthing = 10000000
+	}
+	return tm
+}
+
+//GaussBoaga returns the TransverseMercator Projection of the Roma40 Gauss-Boaga
+//Ovest (zone 1) or Est (zone 2) fuso
+func GaussBoaga(zone int) TransverseMercator {
+	tm := TransverseMercator{
+		Datum:           Roma40Datum,
+		CentralMeridian: 9,
+		ScaleFactor:     k0,
+		FalseEasting:    1500000,
+	}
+	if zone == 2 {
+		tm.CentralMeridian = 15
+		tm.FalseEasting = 2520000
+	}
+	return tm
+}
+
+//ToLatLon implements the Projection interface
+func (tm TransverseMercator) ToLatLon(x, y float64) (Point, error) {
+	unit := tm.Unit
+	if unit == 0 {
+		unit = 1
+	}
+	if tm.ScaleFactor == 0 {
+		return Point{}, fmt.Errorf("%w: scale factor is zero", ErrUnsupportedCRS)
+	}
+
+	lat, lng := inverseTransverseMercator(
+		tm.Datum.Ellipsoid,
+		x*unit-tm.FalseEasting,
+		y*unit-tm.FalseNorthing,
+		tm.CentralMeridian,
+		tm.LatitudeOfOrigin,
+		tm.ScaleFactor,
+	)
+	if math.IsNaN(lat) || math.IsNaN(lng) || lat < -90 || lat > 90 {
+		return Point{}, fmt.Errorf("coordinates (%f, %f) out of the projection domain", x, y)
+	}
+
+	lat, lng = tm.Datum.toWGS84(lat, lng)
+	return Point{lat, lng}, nil
+}
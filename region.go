@@ -1,21 +1,21 @@
 package gomuni
 
 import (
-	"github.com/dhconnelly/rtreego"
 	shp "github.com/jonas-p/go-shp"
-	geo "github.com/kellydunn/golang-geo"
 )
 
 //Region represent an italian Region with its cities
 type Region struct {
-	ID     string  `json:"id,omitempty"`
-	Name   string  `json:"name,omitempty"`
-	Cities []*City `json:"cities,omitempty"`
-
-	BBox       shp.Box `json:"bbox,omitempty"`
-	polygon    *geo.Polygon
-	citiesTree *rtreego.Rtree
-	citiesMap  map[string]*City
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Names      Names             `json:"names,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Cities     []*City           `json:"cities,omitempty"`
+
+	BBox        shp.Box `json:"bbox,omitempty"`
+	polygon     MultiPolygon
+	citiesIndex spatialIndex
+	citiesMap   map[string]*City
 }
 
 //CityGetter can be used to retrive a city from its ID or from a geolocation point
@@ -24,11 +24,9 @@ type CityGetter interface {
 	GetCityByPoint(lat, lng float32) []*City
 }
 
-//Bounds is used to implement the rtreego Spatial interface
-func (r *Region) Bounds() *rtreego.Rect {
-	p1 := rtreego.Point{r.BBox.MinX, r.BBox.MinY}
-	r1, _ := rtreego.NewRect(p1, []float64{r.BBox.MaxX - r.BBox.MinX, r.BBox.MaxY - r.BBox.MinY})
-	return r1
+//Contains check if the current Region contains the passed in Point.
+func (r *Region) Contains(point Point) bool {
+	return r.polygon.Contains(point)
 }
 
 //GetCityByID returns the City with the provided ID
@@ -38,13 +36,9 @@ func (r *Region) GetCityByID(ID string) *City {
 
 //GetCitiesByPoint returns the Cities having their bounding box over the provided geolocation point
 func (r *Region) GetCitiesByPoint(point Point) []*City {
-	location := rtreego.Point{point.Lat, point.Lng}
-	results := r.citiesTree.SearchIntersect(location.ToRect(0.01))
-
 	cities := make([]*City, 0)
-	for _, s := range results {
-		r := s.(*City)
-		cities = append(cities, r)
+	for _, i := range r.citiesIndex.search(pointBox(point, 0.01)) {
+		cities = append(cities, r.Cities[i])
 	}
 
 	return cities
@@ -53,5 +47,15 @@ func (r *Region) GetCitiesByPoint(point Point) []*City {
 func (r *Region) addCity(city *City) {
 	r.Cities = append(r.Cities, city)
 	r.citiesMap[city.ID] = city
-	r.citiesTree.Insert(city)
+}
+
+//Geometry returns the boundary of the Region, with its holes and islands
+func (r *Region) Geometry() Geometry {
+	return r.polygon.Geometry()
+}
+
+//SimplifiedGeometry returns the boundary of the Region simplified with the tolerance in metres.
+//Its borders can diverge from the ones of its neighbors, a Topology simplifies them once for all the units.
+func (r *Region) SimplifiedGeometry(tolerance float64, method SimplifyMethod) Geometry {
+	return r.polygon.Simplify(tolerance, method).Geometry()
 }
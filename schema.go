@@ -0,0 +1,154 @@
+package gomuni
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	levelRegion = "region"
+	levelCity   = "city"
+	levelTown   = "town"
+)
+
+//Schema maps the logical fields of an administrative level to the names of the DBF fields.
+//Empty names are not read, the fields not mapped are kept in the Attributes of the unit.
+type Schema struct {
+	ID        string
+	RegionID  string
+	CityID    string
+	Name      string
+	Shortname string
+
+	//AltName is the field with the name in the other official language, like Bozen for Bolzano
+	AltName string
+
+	//Maincity is the field flagging the metropolitan cities.
+	//IsMaincity reports if the flag is set, if nil the value "1" is expected.
+	Maincity   string
+	IsMaincity func(value string) bool
+}
+
+//Vintage groups the Schemas of the layers of an ISTAT release
+type Vintage struct {
+	Name   string
+	Region Schema
+	City   Schema
+	Town   Schema
+}
+
+//Vintage2016 is the schema of the ISTAT 2016 release (Reg2016, CMProv2016, Com2016)
+var Vintage2016 = Vintage{
+	Name:   "2016",
+	Region: Schema{ID: "COD_REG", Name: "REGIONE"},
+	City:   Schema{ID: "COD_PRO", RegionID: "COD_REG", Name: "PROVINCIA", Shortname: "SIGLA", Maincity: "FLAG_CM"},
+	Town:   Schema{ID: "PRO_COM", RegionID: "COD_REG", CityID: "COD_PRO", Name: "COMUNE", AltName: "NOME_TED"},
+}
+
+//Vintage2017 is the schema of the ISTAT releases from 2017 to 2019 (Reg, ProvCM, Com)
+var Vintage2017 = Vintage{
+	Name:   "2017",
+	Region: Schema{ID: "COD_REG", Name: "DEN_REG"},
+	City: Schema{ID: "COD_PROV", RegionID: "COD_REG", Name: "DEN_PCM", Shortname: "SIGLA", Maincity: "COD_CM",
+		IsMaincity: func(value string) bool { return value != "" && value != "0" }},
+	Town: Schema{ID: "PRO_COM", RegionID: "COD_REG", CityID: "COD_PROV", Name: "COMUNE", AltName: "COMUNE_A"},
+}
+
+//Vintage2020 is the schema of the ISTAT releases from 2020, with the supra-municipal territorial units (ProvCM)
+var Vintage2020 = Vintage{
+	Name:   "2020",
+	Region: Schema{ID: "COD_REG", Name: "DEN_REG"},
+	City: Schema{ID: "COD_PROV", RegionID: "COD_REG", Name: "DEN_UTS", Shortname: "SIGLA", Maincity: "TIPO_UTS",
+		IsMaincity: func(value string) bool { return strings.Contains(strings.ToLower(value), "metropolitana") }},
+	Town: Schema{ID: "PRO_COM", RegionID: "COD_REG", CityID: "COD_PROV", Name: "COMUNE", AltName: "COMUNE_A"},
+}
+
+//Vintages are the known ISTAT releases, in the order used to detect the schema of a layer
+var Vintages = []Vintage{Vintage2020, Vintage2017, Vintage2016}
+
+func (v Vintage) schema(level string) Schema {
+	switch level {
+	case levelRegion:
+		return v.Region
+	case levelCity:
+		return v.City
+	}
+	return v.Town
+}
+
+//required returns the DBF fields that must be present to use the Schema
+func (s Schema) required() []string {
+	fields := make([]string, 0, 4)
+	for _, f := range []string{s.ID, s.RegionID, s.CityID, s.Name} {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+//mapped returns all the DBF fields read by the Schema
+func (s Schema) mapped() map[string]bool {
+	mapped := make(map[string]bool)
+	for _, f := range []string{s.ID, s.RegionID, s.CityID, s.Name, s.Shortname, s.Maincity, s.AltName} {
+		if f != "" {
+			mapped[f] = true
+		}
+	}
+	return mapped
+}
+
+//maincity reports if the record is flagged as a metropolitan city
+func (s Schema) maincity(record map[string]string) bool {
+	if s.Maincity == "" {
+		return false
+	}
+	value := record[s.Maincity]
+	if s.IsMaincity == nil {
+		return value == "1"
+	}
+	return s.IsMaincity(value)
+}
+
+//attributes returns the fields of the record not mapped by the Schema
+func (s Schema) attributes(record map[string]string) map[string]string {
+	mapped := s.mapped()
+	attributes := make(map[string]string)
+	for k, v := range record {
+		if !mapped[k] {
+			attributes[k] = v
+		}
+	}
+	return attributes
+}
+
+//matches checks if all the required fields of the Schema are in the DBF header
+func (s Schema) matches(header map[string]bool) bool {
+	for _, f := range s.required() {
+		if !header[f] {
+			return false
+		}
+	}
+	return true
+}
+
+//detectSchema returns the Schema of the level matching the DBF header.
+//If the Options have a Vintage it is used without detection.
+func detectSchema(level string, fields []string, opts Options) (Schema, error) {
+	header := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		header[f] = true
+	}
+
+	vintages := Vintages
+	if opts.Vintage != nil {
+		vintages = []Vintage{*opts.Vintage}
+	}
+
+	for _, v := range vintages {
+		if schema := v.schema(level); schema.matches(header) {
+			return schema, nil
+		}
+	}
+	return Schema{}, fmt.Errorf("%w for the %s layer with fields %s", ErrUnknownSchema, level, strings.Join(fields, ", "))
+}
@@ -0,0 +1,66 @@
+package gomuni
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_detectSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  string
+		fields []string
+		want   Schema
+	}{
+		{"2016 regions", levelRegion, []string{"COD_REG", "REGIONE", "SHAPE_Leng", "SHAPE_Area"}, Vintage2016.Region},
+		{"2016 cities", levelCity, []string{"COD_REG", "COD_CM", "COD_PRO", "PROVINCIA", "SIGLA", "FLAG_CM", "SHAPE_Leng", "SHAPE_Area"}, Vintage2016.City},
+		{"2016 towns", levelTown, []string{"COD_REG", "COD_CM", "COD_PRO", "PRO_COM", "COMUNE", "NOME_TED", "FLAG_CM", "SHAPE_Leng", "SHAPE_Area"}, Vintage2016.Town},
+		{"2017 regions", levelRegion, []string{"COD_RIP", "COD_REG", "DEN_REG", "SHAPE_Leng", "SHAPE_Area"}, Vintage2017.Region},
+		{"2017 cities", levelCity, []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "COD_PCM", "DEN_PROV", "DEN_CM", "DEN_PCM", "SIGLA"}, Vintage2017.City},
+		{"2017 towns", levelTown, []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "PRO_COM", "PRO_COM_T", "COMUNE", "COMUNE_A"}, Vintage2017.Town},
+		{"2020 cities", levelCity, []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "COD_UTS", "DEN_PROV", "DEN_CM", "DEN_UTS", "SIGLA", "TIPO_UTS"}, Vintage2020.City},
+		{"2020 towns", levelTown, []string{"COD_RIP", "COD_REG", "COD_PROV", "COD_CM", "COD_UTS", "PRO_COM", "PRO_COM_T", "COMUNE", "COMUNE_A", "CC_UTS"}, Vintage2020.Town},
+		{"reordered towns", levelTown, []string{"COMUNE", "PRO_COM", "COD_PRO", "COD_REG", "NOME_TED"}, Vintage2016.Town},
+		{"extra columns", levelCity, []string{"AREA_KMQ", "COD_REG", "NOTE", "COD_PROV", "DEN_PCM", "SIGLA", "COD_CM", "POP_2017"}, Vintage2017.City},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectSchema(tt.level, tt.fields, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != tt.want.ID || got.RegionID != tt.want.RegionID || got.CityID != tt.want.CityID ||
+				got.Name != tt.want.Name || got.Maincity != tt.want.Maincity || got.AltName != tt.want.AltName {
+				t.Errorf("detectSchema() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := detectSchema(levelRegion, []string{"CODE", "NAME"}, Options{}); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("expected ErrUnknownSchema, got %v", err)
+	}
+	// a forced Vintage is not detected
+	fields := []string{"COD_RIP", "COD_REG", "DEN_REG"}
+	if _, err := detectSchema(levelRegion, fields, Options{Vintage: &Vintage2016}); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("expected ErrUnknownSchema with the 2016 Vintage, got %v", err)
+	}
+}
+
+func Test_Schema(t *testing.T) {
+	record := map[string]string{"COD_REG": "1", "COD_PROV": "1", "DEN_UTS": "Torino", "SIGLA": "TO",
+		"TIPO_UTS": "Citta metropolitana", "COD_CM": "201", "SHAPE_AREA": "6827"}
+
+	schema := Vintage2020.City
+	if !schema.maincity(record) {
+		t.Error("Torino is not flagged as a metropolitan city")
+	}
+	want := map[string]string{"COD_CM": "201", "SHAPE_AREA": "6827"}
+	if got := schema.attributes(record); !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes() = %v, want %v", got, want)
+	}
+
+	if Vintage2017.City.maincity(map[string]string{"COD_CM": "0"}) || !Vintage2016.City.maincity(map[string]string{"FLAG_CM": "1"}) {
+		t.Error("unexpected metropolitan city flags")
+	}
+}
@@ -0,0 +1,159 @@
+package gomuni
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//shapefileRecord is a polygon read from a .shp file, with its DBF row
+type shapefileRecord struct {
+	n       int
+	polygon *shp.Polygon
+}
+
+//decodeShp decodes the polygons of the content of a .shp file.
+//The Null shapes are skipped, the other shape types are reported as errors.
+func decodeShp(data []byte) ([]shapefileRecord, error) {
+	if len(data) < 100 {
+		return nil, errors.New("shapefile header too short")
+	}
+	if code := binary.BigEndian.Uint32(data); code != 9994 {
+		return nil, fmt.Errorf("invalid shapefile file code %d", code)
+	}
+
+	records := make([]shapefileRecord, 0)
+	for offset, n := 100, 0; offset+12 <= len(data); n++ {
+		contentLength := int(binary.BigEndian.Uint32(data[offset+4:])) * 2
+		start := offset + 8
+		end := start + contentLength
+		if contentLength < 4 || end > len(data) {
+			return nil, fmt.Errorf("record %d: truncated content", n)
+		}
+		offset = end
+
+		content := data[start:end]
+		switch shapeType := shp.ShapeType(binary.LittleEndian.Uint32(content)); shapeType {
+		case shp.NULL:
+			continue
+		case shp.POLYGON, shp.POLYGONZ, shp.POLYGONM:
+			polygon, err := decodePolygon(content[4:])
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", n, err)
+			}
+			records = append(records, shapefileRecord{n, polygon})
+		default:
+			return nil, fmt.Errorf("record %d: unsupported shape type %d", n, shapeType)
+		}
+	}
+
+	return records, nil
+}
+
+//decodePolygon decodes the box, the parts and the points of a polygon, ignoring the Z and M values
+func decodePolygon(content []byte) (*shp.Polygon, error) {
+	if len(content) < 40 {
+		return nil, errors.New("polygon too short")
+	}
+
+	p := &shp.Polygon{
+		Box: shp.Box{
+			MinX: readFloat64(content[0:]),
+			MinY: readFloat64(content[8:]),
+			MaxX: readFloat64(content[16:]),
+			MaxY: readFloat64(content[24:]),
+		},
+		NumParts:  int32(binary.LittleEndian.Uint32(content[32:])),
+		NumPoints: int32(binary.LittleEndian.Uint32(content[36:])),
+	}
+
+	if p.NumParts < 0 || p.NumPoints < 0 || 40+int(p.NumParts)*4+int(p.NumPoints)*16 > len(content) {
+		return nil, fmt.Errorf("invalid polygon with %d parts and %d points", p.NumParts, p.NumPoints)
+	}
+
+	p.Parts = make([]int32, p.NumParts)
+	offset := 40
+	for i := range p.Parts {
+		p.Parts[i] = int32(binary.LittleEndian.Uint32(content[offset:]))
+		offset += 4
+	}
+
+	p.Points = make([]shp.Point, p.NumPoints)
+	for i := range p.Points {
+		p.Points[i] = shp.Point{X: readFloat64(content[offset:]), Y: readFloat64(content[offset+8:])}
+		offset += 16
+	}
+
+	return p, nil
+}
+
+func readFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+//dbfTable is the content of a .dbf file
+type dbfTable struct {
+	fields  []string
+	records [][]string
+}
+
+//decodeDbf decodes the field names and all the records of the content of a .dbf file.
+//The values are trimmed, the deleted records are kept to preserve the row numbers.
+func decodeDbf(data []byte) (*dbfTable, error) {
+	if len(data) < 32 {
+		return nil, errors.New("dbf header too short")
+	}
+
+	numRecords := int(binary.LittleEndian.Uint32(data[4:]))
+	headerLength := int(binary.LittleEndian.Uint16(data[8:]))
+	recordLength := int(binary.LittleEndian.Uint16(data[10:]))
+	if headerLength > len(data) || recordLength < 1 {
+		return nil, errors.New("invalid dbf header")
+	}
+
+	table := &dbfTable{}
+	sizes := make([]int, 0)
+	for offset := 32; offset+32 <= headerLength && data[offset] != 0x0d; offset += 32 {
+		name := strings.TrimRight(string(data[offset:offset+11]), "\x00 ")
+		table.fields = append(table.fields, name)
+		sizes = append(sizes, int(data[offset+16]))
+	}
+
+	table.records = make([][]string, 0, numRecords)
+	for n := 0; n < numRecords; n++ {
+		start := headerLength + n*recordLength
+		if start+recordLength > len(data) {
+			return nil, fmt.Errorf("record %d: truncated dbf", n)
+		}
+
+		row := make([]string, len(sizes))
+		offset := start + 1 // skip the deletion flag
+		for i, size := range sizes {
+			if offset+size > start+recordLength {
+				return nil, fmt.Errorf("record %d: field %s out of the record", n, table.fields[i])
+			}
+			row[i] = strings.TrimSpace(decodeText(data[offset : offset+size]))
+			offset += size
+		}
+		table.records = append(table.records, row)
+	}
+
+	return table, nil
+}
+
+//decodeText returns the UTF-8 string of a DBF value, converting it from Latin-1 if needed
+func decodeText(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,209 @@
+package gomuni
+
+import (
+	"container/heap"
+	"math"
+)
+
+//SimplifyMethod is the algorithm removing the points of the boundaries
+type SimplifyMethod int
+
+const (
+	//DouglasPeucker keeps the points farther than the tolerance from the simplified line
+	DouglasPeucker SimplifyMethod = iota
+	//Visvalingam removes the points making the smallest triangles with their neighbors,
+	//while their area is less than the tolerance squared
+	Visvalingam
+)
+
+//Simplify returns a copy of the MultiPolygon with less points, for a tolerance in metres.
+//Each ring is simplified on its own, the borders shared by two units can diverge:
+//the Topology of the Country simplifies them once for both the units.
+func (m MultiPolygon) Simplify(tolerance float64, method SimplifyMethod) MultiPolygon {
+	simplified := make(MultiPolygon, 0, len(m))
+	for _, p := range m {
+		polygon := make(Polygon, 0, len(p))
+		for _, ring := range p {
+			if len(ring) == 0 {
+				continue
+			}
+			closed := ring
+			if ring[0] != ring[len(ring)-1] {
+				closed = append(append(Ring{}, ring...), ring[0])
+			}
+			polygon = append(polygon, simplifyLine(closed, closed, tolerance, method))
+		}
+		if len(polygon) > 0 {
+			simplified = append(simplified, polygon)
+		}
+	}
+	return simplified
+}
+
+//simplifyLine returns the points of the line kept by the method with the tolerance in metres,
+//the degrees are the same points in longitude and latitude to measure their distances.
+//The first and the last points are kept, and a closed line keeps at least 3 distinct points to remain a ring.
+func simplifyLine(line, degrees Ring, tolerance float64, method SimplifyMethod) Ring {
+	n := len(line)
+	if tolerance <= 0 || n < 3 {
+		return append(Ring{}, line...)
+	}
+
+	// the points are projected in metres on the plane tangent to the Earth at the center of the line
+	lat0 := 0.0
+	for _, p := range degrees {
+		lat0 += p.Lat
+	}
+	lat0 /= float64(n)
+	metresLat := rad(earthRadius * 1000)
+	metresLng := metresLat * math.Cos(rad(lat0))
+	xy := make([][2]float64, n)
+	for i, p := range degrees {
+		xy[i] = [2]float64{p.Lng * metresLng, p.Lat * metresLat}
+	}
+
+	minKept := 3
+	if line[0] == line[n-1] {
+		minKept = 4
+	}
+	minKept = minInt(minKept, n)
+
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+	if method == Visvalingam {
+		visvalingam(xy, tolerance*tolerance, keep, minKept)
+	} else {
+		douglasPeucker(xy, tolerance, keep, minKept)
+	}
+
+	simplified := make(Ring, 0)
+	for i, p := range line {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+//distanceFromSegment returns the distance of the point p from the segment between a and b
+func distanceFromSegment(p, a, b [2]float64) float64 {
+	return segmentDistance(a[0]-p[0], a[1]-p[1], b[0]-p[0], b[1]-p[1])
+}
+
+//douglasPeucker keeps the farthest point from the segment between two kept points, while it is farther than the tolerance.
+//Then it keeps the farthest points until minKept points are kept.
+func douglasPeucker(xy [][2]float64, tolerance float64, keep []bool, minKept int) {
+	kept := 2
+
+	// farthest returns the farthest point between the kept points from and to, -1 if none
+	farthest := func(from, to int) (int, float64) {
+		far, farDistance := -1, -1.0
+		for i := from + 1; i < to; i++ {
+			if d := distanceFromSegment(xy[i], xy[from], xy[to]); d > farDistance {
+				far, farDistance = i, d
+			}
+		}
+		return far, farDistance
+	}
+
+	stack := [][2]int{{0, len(xy) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if far, d := farthest(span[0], span[1]); far >= 0 && d > tolerance {
+			keep[far] = true
+			kept++
+			stack = append(stack, [2]int{span[0], far}, [2]int{far, span[1]})
+		}
+	}
+
+	for kept < minKept {
+		far, farDistance := -1, -1.0
+		from := 0
+		for to := 1; to < len(xy); to++ {
+			if !keep[to] {
+				continue
+			}
+			if i, d := farthest(from, to); i >= 0 && d > farDistance {
+				far, farDistance = i, d
+			}
+			from = to
+		}
+		if far < 0 {
+			return
+		}
+		keep[far] = true
+		kept++
+	}
+}
+
+//visvalingam removes the point making the smallest triangle with its neighbors while its area is less than the threshold,
+//keeping at least minKept points. The area of a point is never less than the one of a point removed before it,
+//so that the points are removed in order of significance.
+func visvalingam(xy [][2]float64, threshold float64, keep []bool, minKept int) {
+	n := len(xy)
+	prev := make([]int, n)
+	next := make([]int, n)
+	areas := make([]float64, n)
+	removed := make([]bool, n)
+
+	triangle := func(a, b, c [2]float64) float64 {
+		return math.Abs((b[0]-a[0])*(c[1]-a[1])-(c[0]-a[0])*(b[1]-a[1])) / 2
+	}
+
+	queue := &areaQueue{}
+	for i := 1; i < n-1; i++ {
+		prev[i], next[i] = i-1, i+1
+		areas[i] = triangle(xy[i-1], xy[i], xy[i+1])
+		heap.Push(queue, areaItem{i, areas[i]})
+	}
+
+	remaining := n
+	for queue.Len() > 0 && remaining > minKept {
+		item := heap.Pop(queue).(areaItem)
+		if removed[item.index] || item.area != areas[item.index] {
+			continue
+		}
+		if item.area >= threshold {
+			break
+		}
+
+		i := item.index
+		removed[i] = true
+		remaining--
+		next[prev[i]], prev[next[i]] = next[i], prev[i]
+
+		for _, j := range []int{prev[i], next[i]} {
+			if j == 0 || j == n-1 {
+				continue
+			}
+			areas[j] = math.Max(item.area, triangle(xy[prev[j]], xy[j], xy[next[j]]))
+			heap.Push(queue, areaItem{j, areas[j]})
+		}
+	}
+
+	for i := range keep {
+		keep[i] = !removed[i]
+	}
+}
+
+//areaItem is a point of a line with the area of its triangle
+type areaItem struct {
+	index int
+	area  float64
+}
+
+//areaQueue is a min-heap of the points by their area
+type areaQueue []areaItem
+
+func (q areaQueue) Len() int            { return len(q) }
+func (q areaQueue) Less(i, j int) bool  { return q[i].area < q[j].area }
+func (q areaQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *areaQueue) Push(x interface{}) { *q = append(*q, x.(areaItem)) }
+func (q *areaQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
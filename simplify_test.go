@@ -0,0 +1,112 @@
+package gomuni
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_simplifyLine(t *testing.T) {
+	// a line along the parallel 45 with a noise of about 10 cm and a peak of about 1 km in the middle
+	line := make(Ring, 101)
+	for i := range line {
+		line[i] = Point{Lat: 45 + float64(i%2)*1e-6, Lng: 7 + float64(i)*0.01}
+	}
+	line[50].Lat = 45.01
+
+	for _, method := range []SimplifyMethod{DouglasPeucker, Visvalingam} {
+		simplified := simplifyLine(line, line, 100, method)
+		if len(simplified) != 5 {
+			t.Errorf("method %d: got %d points, want 5: %v", method, len(simplified), simplified)
+			continue
+		}
+		if simplified[0] != line[0] || simplified[2] != line[50] || simplified[4] != line[100] {
+			t.Errorf("method %d: the ends and the peak are not kept: %v", method, simplified)
+		}
+		if kept := simplifyLine(line, line, 0.01, method); len(kept) != len(line) {
+			t.Errorf("method %d: got %d points with a tolerance of 1 cm, want %d", method, len(kept), len(line))
+		}
+	}
+
+	// a closed ring keeps a triangle with any tolerance
+	ring := Ring{{45, 7}, {45.001, 7}, {45.001, 7.001}, {45, 7.001}, {45, 7}}
+	for _, method := range []SimplifyMethod{DouglasPeucker, Visvalingam} {
+		if simplified := simplifyLine(ring, ring, 1e6, method); len(simplified) != 4 || simplified[0] != simplified[3] {
+			t.Errorf("method %d: unexpected ring %v", method, simplified)
+		}
+	}
+}
+
+func Test_Simplify(t *testing.T) {
+	country := newTestCountry()
+	for _, r := range country.Regions {
+		m := r.polygon.Simplify(1000, DouglasPeucker)
+		if len(m) != len(r.polygon) {
+			t.Fatalf("region %s: got %d polygons, want %d", r.ID, len(m), len(r.polygon))
+		}
+		for _, p := range []Point{{45, 7.5}, {45, 10.5}, {45, 11}, {43, 7.5}} {
+			if m.Contains(p) != r.Contains(p) {
+				t.Errorf("region %s: Contains(%v) = %v", r.ID, p, m.Contains(p))
+			}
+		}
+		if g := r.SimplifiedGeometry(1000, Visvalingam); len(g.Coordinates) != len(r.Geometry().Coordinates) {
+			t.Errorf("region %s: unexpected simplified geometry %v", r.ID, g)
+		}
+	}
+}
+
+func Test_TopologySimplify(t *testing.T) {
+	// two towns sharing a border along the meridian 8 with a noise of about 10 metres
+	border := make(Ring, 0)
+	for i := 0; i <= 100; i++ {
+		border = append(border, Point{Lat: 45 + float64(i)*0.01, Lng: 8 + float64(i%2)*1e-4})
+	}
+	west := append(Ring{{46, 7}, {45, 7}}, border...)
+	west = append(west, Point{46, 7})
+	east := append(Ring{{46, 9}, {45, 9}}, border...)
+	east = append(east, Point{46, 9})
+
+	a := &Town{ID: "a", polygon: MultiPolygon{{west}}}
+	b := &Town{ID: "b", polygon: MultiPolygon{{east}}}
+	country := &Country{Regions: []*Region{{
+		ID:      "r",
+		polygon: MultiPolygon{{Ring{{45, 7}, {46, 7}, {46, 9}, {45, 9}, {45, 7}}}},
+		Cities:  []*City{{ID: "c", Towns: []*Town{a, b}}},
+	}}}
+
+	for _, method := range []SimplifyMethod{DouglasPeucker, Visvalingam} {
+		topology := country.Topology(TopologyOptions{Tolerance: 100, Method: method})
+
+		onBorder := func(id string) map[Point]bool {
+			points := make(map[Point]bool)
+			for _, p := range topology.MultiPolygon(TopologyTowns, id)[0][0] {
+				if math.Abs(p.Lng-8) < 0.01 {
+					points[p] = true
+				}
+			}
+			return points
+		}
+		westBorder, eastBorder := onBorder("a"), onBorder("b")
+		if len(westBorder) >= len(border) || len(westBorder) < 2 {
+			t.Errorf("method %d: got %d points on the border, want less than %d", method, len(westBorder), len(border))
+		}
+		// the simplified border is the same for both the towns, without gaps
+		if len(westBorder) != len(eastBorder) {
+			t.Errorf("method %d: got %d and %d points on the border", method, len(westBorder), len(eastBorder))
+		}
+		for p := range westBorder {
+			if !eastBorder[p] {
+				t.Errorf("method %d: the point %v is not on the border of both the towns", method, p)
+			}
+		}
+
+		for _, p := range []Point{{45.5, 7.5}, {45.5, 8.5}} {
+			if m := topology.MultiPolygon(TopologyRegions, "r"); !m.Contains(p) {
+				t.Errorf("method %d: the region does not contain %v", method, p)
+			}
+		}
+	}
+
+	if m := (&Topology{}).MultiPolygon(TopologyTowns, "a"); m != nil {
+		t.Errorf("got %v from a decoded Topology, want nil", m)
+	}
+}
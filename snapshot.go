@@ -0,0 +1,212 @@
+package gomuni
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//snapshotMagic identifies a gomuni snapshot, followed by the format version
+var snapshotMagic = [6]byte{'G', 'O', 'M', 'U', 'N', 'I'}
+
+const snapshotVersion uint16 = 3
+
+//The snapshot holds the projected geometries and the attributes of every unit,
+//with the bulk-loaded spatial indexes of their children that are read as they are.
+type snapshot struct {
+	Regions      []snapshotRegion
+	RegionsIndex spatialIndex
+}
+
+type snapshotRegion struct {
+	ID          string
+	Name        string
+	Names       Names
+	Attributes  map[string]string
+	BBox        shp.Box
+	Polygon     MultiPolygon
+	Cities      []snapshotCity
+	CitiesIndex spatialIndex
+}
+
+type snapshotCity struct {
+	ID         string
+	RegionID   string
+	Name       string
+	Names      Names
+	Shortname  string
+	Maincity   bool
+	Attributes map[string]string
+	BBox       shp.Box
+	Polygon    MultiPolygon
+	Towns      []snapshotTown
+	TownsIndex spatialIndex
+}
+
+type snapshotTown struct {
+	ID         string
+	RegionID   string
+	CityID     string
+	Name       string
+	Names      Names
+	Attributes map[string]string
+	BBox       shp.Box
+	Polygon    MultiPolygon
+}
+
+//WriteSnapshot writes the Country in the binary snapshot format read by LoadSnapshot.
+//The snapshot is made by a header with the format version, the payload and its CRC32 checksum.
+func (c *Country) WriteSnapshot(w io.Writer) error {
+	s := snapshot{Regions: make([]snapshotRegion, 0, len(c.Regions)), RegionsIndex: c.regionsIndex}
+	for _, r := range c.Regions {
+		region := snapshotRegion{
+			ID:          r.ID,
+			Name:        r.Name,
+			Names:       r.Names,
+			Attributes:  r.Attributes,
+			BBox:        r.BBox,
+			Polygon:     r.polygon,
+			Cities:      make([]snapshotCity, 0, len(r.Cities)),
+			CitiesIndex: r.citiesIndex,
+		}
+
+		for _, ci := range r.Cities {
+			city := snapshotCity{
+				ID:         ci.ID,
+				RegionID:   ci.RegionID,
+				Name:       ci.Name,
+				Names:      ci.Names,
+				Shortname:  ci.Shortname,
+				Maincity:   ci.Maincity,
+				Attributes: ci.Attributes,
+				BBox:       ci.BBox,
+				Polygon:    ci.polygon,
+				Towns:      make([]snapshotTown, 0, len(ci.Towns)),
+				TownsIndex: ci.townsIndex,
+			}
+
+			for _, t := range ci.Towns {
+				city.Towns = append(city.Towns, snapshotTown{
+					ID:         t.ID,
+					RegionID:   t.RegionID,
+					CityID:     t.CityID,
+					Name:       t.Name,
+					Names:      t.Names,
+					Attributes: t.Attributes,
+					BBox:       t.BBox,
+					Polygon:    t.polygon,
+				})
+			}
+			region.Cities = append(region.Cities, city)
+		}
+		s.Regions = append(s.Regions, region)
+	}
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(s); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(snapshotMagic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, snapshotVersion); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload.Bytes()); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(payload.Bytes()))
+}
+
+//LoadSnapshot loads a Country from a snapshot written by WriteSnapshot
+func LoadSnapshot(r io.Reader) (*Country, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := len(snapshotMagic) + 2
+	if len(data) < headerLen+4 || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidSnapshot)
+	}
+	if version := binary.BigEndian.Uint16(data[len(snapshotMagic):]); version != snapshotVersion {
+		return nil, fmt.Errorf("%w: version %d, expected %d", ErrInvalidSnapshot, version, snapshotVersion)
+	}
+
+	payload := data[headerLen : len(data)-4]
+	if checksum := binary.BigEndian.Uint32(data[len(data)-4:]); checksum != crc32.ChecksumIEEE(payload) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+
+	var s snapshot
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	if !s.RegionsIndex.valid(len(s.Regions)) {
+		return nil, fmt.Errorf("%w: invalid regions index", ErrInvalidSnapshot)
+	}
+	country := newCountry()
+	country.regionsIndex = s.RegionsIndex
+	for _, r := range s.Regions {
+		if !r.CitiesIndex.valid(len(r.Cities)) {
+			return nil, fmt.Errorf("%w: invalid cities index of region %s", ErrInvalidSnapshot, r.ID)
+		}
+		region := &Region{
+			ID:          r.ID,
+			Name:        r.Name,
+			Names:       r.Names,
+			Attributes:  r.Attributes,
+			Cities:      make([]*City, 0, len(r.Cities)),
+			BBox:        r.BBox,
+			polygon:     r.Polygon,
+			citiesIndex: r.CitiesIndex,
+			citiesMap:   make(map[string]*City),
+		}
+
+		for _, c := range r.Cities {
+			if !c.TownsIndex.valid(len(c.Towns)) {
+				return nil, fmt.Errorf("%w: invalid towns index of city %s", ErrInvalidSnapshot, c.ID)
+			}
+			city := &City{
+				ID:         c.ID,
+				RegionID:   c.RegionID,
+				Name:       c.Name,
+				Names:      c.Names,
+				Shortname:  c.Shortname,
+				Maincity:   c.Maincity,
+				Attributes: c.Attributes,
+				Towns:      make([]*Town, 0, len(c.Towns)),
+				BBox:       c.BBox,
+				polygon:    c.Polygon,
+				townsIndex: c.TownsIndex,
+				townsMap:   make(map[string]*Town),
+			}
+
+			for _, t := range c.Towns {
+				city.addTown(&Town{
+					ID:         t.ID,
+					RegionID:   t.RegionID,
+					CityID:     t.CityID,
+					Name:       t.Name,
+					Names:      t.Names,
+					Attributes: t.Attributes,
+					BBox:       t.BBox,
+					polygon:    t.Polygon,
+				})
+			}
+			region.addCity(city)
+		}
+		country.addRegion(region)
+	}
+	country.buildIndexes()
+
+	return country, nil
+}
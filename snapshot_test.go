@@ -0,0 +1,67 @@
+package gomuni
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Snapshot(t *testing.T) {
+	country := newTestCountry()
+
+	var buf bytes.Buffer
+	if err := country.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	loaded, err := LoadSnapshot(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, point := range []Point{{45, 7.5}, {45, 8.5}, {45, 11}, {44.5, 11.5}, {40, 8}} {
+		want := country.FindTownByPoint(point)
+		got := loaded.FindTownByPoint(point)
+		if (want == nil) != (got == nil) || (want != nil && !reflect.DeepEqual(*want, *got)) {
+			t.Errorf("FindTownByPoint(%v) = %+v, want %+v", point, got, want)
+		}
+	}
+
+	// the spatial indexes are read from the snapshot, not rebuilt
+	if !reflect.DeepEqual(loaded.regionsIndex, country.regionsIndex) {
+		t.Error("the regions index differs from the written one")
+	}
+	for i, r := range loaded.Regions {
+		if !reflect.DeepEqual(r.citiesIndex, country.Regions[i].citiesIndex) {
+			t.Errorf("the cities index of region %s differs from the written one", r.ID)
+		}
+	}
+	towns := loaded.Regions[0].Cities[0].townsIndex
+	if len(towns.Items) == 0 || !reflect.DeepEqual(towns, country.Regions[0].Cities[0].townsIndex) {
+		t.Errorf("the towns index %+v differs from the written one", towns)
+	}
+
+	invalid := *country
+	invalid.regionsIndex = spatialIndex{}
+	buf.Reset()
+	if err := invalid.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(&buf); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for a snapshot without the regions index, got %v", err)
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if _, err := LoadSnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for a corrupted snapshot, got %v", err)
+	}
+
+	outdated := append([]byte(nil), data...)
+	outdated[len(snapshotMagic)+1]++
+	if _, err := LoadSnapshot(bytes.NewReader(outdated)); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for another version, got %v", err)
+	}
+}
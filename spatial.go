@@ -0,0 +1,150 @@
+package gomuni
+
+import (
+	"math"
+	"sort"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//spatialNodeSize is the number of children of each node of the spatial indexes
+const spatialNodeSize = 16
+
+//spatialIndex is a static R-tree of the bounding boxes of the units, bulk-loaded with the Sort-Tile-Recursive algorithm.
+//It is made by flat slices, that are stored as they are in the snapshots.
+type spatialIndex struct {
+	//Boxes are the boxes of the nodes level by level, from the leaves to the root
+	Boxes []shp.Box
+	//Items are the positions of the units of the leaves
+	Items []int
+	//Levels are the offsets of the levels in Boxes, followed by its length
+	Levels []int
+}
+
+//newSpatialIndex bulk-loads the index of the bounding boxes, the items are their positions
+func newSpatialIndex(boxes []shp.Box) spatialIndex {
+	n := len(boxes)
+	if n == 0 {
+		return spatialIndex{}
+	}
+
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	centerX := func(i int) float64 { return boxes[i].MinX + boxes[i].MaxX }
+	centerY := func(i int) float64 { return boxes[i].MinY + boxes[i].MaxY }
+
+	// the leaves are sorted by X in vertical slices, each slice by Y
+	sort.SliceStable(items, func(i, j int) bool { return centerX(items[i]) < centerX(items[j]) })
+	leaves := (n + spatialNodeSize - 1) / spatialNodeSize
+	sliceSize := int(math.Ceil(math.Sqrt(float64(leaves)))) * spatialNodeSize
+	for start := 0; start < n; start += sliceSize {
+		slice := items[start:minInt(start+sliceSize, n)]
+		sort.SliceStable(slice, func(i, j int) bool { return centerY(slice[i]) < centerY(slice[j]) })
+	}
+
+	index := spatialIndex{Boxes: make([]shp.Box, 0, n+leaves+1), Items: items, Levels: []int{0}}
+	for _, i := range items {
+		index.Boxes = append(index.Boxes, boxes[i])
+	}
+	for start, end := 0, n; end-start > 1; start, end = end, len(index.Boxes) {
+		for i := start; i < end; i += spatialNodeSize {
+			box := index.Boxes[i]
+			for _, child := range index.Boxes[i+1 : minInt(i+spatialNodeSize, end)] {
+				box.Extend(child)
+			}
+			index.Boxes = append(index.Boxes, box)
+		}
+		index.Levels = append(index.Levels, end)
+	}
+	index.Levels = append(index.Levels, len(index.Boxes))
+	return index
+}
+
+//valid checks that the index of n items is well formed, as one read from a snapshot
+func (index spatialIndex) valid(n int) bool {
+	if n == 0 {
+		return len(index.Boxes) == 0 && len(index.Items) == 0
+	}
+	if len(index.Items) != n || len(index.Levels) < 2 || index.Levels[0] != 0 || index.Levels[1] != n ||
+		index.Levels[len(index.Levels)-1] != len(index.Boxes) || index.Levels[len(index.Levels)-2] != len(index.Boxes)-1 {
+		return false
+	}
+	for l := 1; l+1 < len(index.Levels); l++ {
+		nodes, children := index.Levels[l+1]-index.Levels[l], index.Levels[l]-index.Levels[l-1]
+		if nodes != (children+spatialNodeSize-1)/spatialNodeSize {
+			return false
+		}
+	}
+	seen := make([]bool, n)
+	for _, i := range index.Items {
+		if i < 0 || i >= n || seen[i] {
+			return false
+		}
+		seen[i] = true
+	}
+	return true
+}
+
+//search returns the positions of the items having their bounding box intersecting the box, in order
+func (index spatialIndex) search(box shp.Box) []int {
+	results := make([]int, 0)
+	if len(index.Boxes) == 0 {
+		return results
+	}
+
+	type node struct{ level, i int }
+	root := len(index.Levels) - 2
+	stack := []node{{root, index.Levels[root]}}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !boxesOverlap(index.Boxes[n.i], box) {
+			continue
+		}
+		if n.level == 0 {
+			results = append(results, index.Items[n.i])
+			continue
+		}
+		first := index.Levels[n.level-1] + (n.i-index.Levels[n.level])*spatialNodeSize
+		for child := first; child < minInt(first+spatialNodeSize, index.Levels[n.level]); child++ {
+			stack = append(stack, node{n.level - 1, child})
+		}
+	}
+
+	sort.Ints(results)
+	return results
+}
+
+//pointBox returns the box around the Point with the tolerance in degrees, with the latitudes on the X axis
+func pointBox(point Point, tolerance float64) shp.Box {
+	return shp.Box{MinX: point.Lat - tolerance, MinY: point.Lng - tolerance, MaxX: point.Lat + tolerance, MaxY: point.Lng + tolerance}
+}
+
+//buildSpatialIndexes bulk-loads the spatial indexes of the Regions, Cities and Towns
+func (c *Country) buildSpatialIndexes() {
+	boxes := make([]shp.Box, len(c.Regions))
+	for i, r := range c.Regions {
+		boxes[i] = r.BBox
+		r.buildSpatialIndexes()
+	}
+	c.regionsIndex = newSpatialIndex(boxes)
+}
+
+func (r *Region) buildSpatialIndexes() {
+	boxes := make([]shp.Box, len(r.Cities))
+	for i, city := range r.Cities {
+		boxes[i] = city.BBox
+		city.buildSpatialIndex()
+	}
+	r.citiesIndex = newSpatialIndex(boxes)
+}
+
+func (c *City) buildSpatialIndex() {
+	boxes := make([]shp.Box, len(c.Towns))
+	for i, t := range c.Towns {
+		boxes[i] = t.BBox
+	}
+	c.townsIndex = newSpatialIndex(boxes)
+}
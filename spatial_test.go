@@ -0,0 +1,50 @@
+package gomuni
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+func Test_spatialIndex(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomBox := func(size float64) shp.Box {
+		x, y := random.Float64()*10, random.Float64()*10
+		return shp.Box{MinX: x, MinY: y, MaxX: x + random.Float64()*size, MaxY: y + random.Float64()*size}
+	}
+
+	for _, n := range []int{0, 1, spatialNodeSize, spatialNodeSize + 1, 1000} {
+		boxes := make([]shp.Box, n)
+		for i := range boxes {
+			boxes[i] = randomBox(0.5)
+		}
+		index := newSpatialIndex(boxes)
+		if !index.valid(n) {
+			t.Fatalf("the index of %d boxes is not valid: %+v", n, index.Levels)
+		}
+
+		for q := 0; q < 50; q++ {
+			query := randomBox(2)
+			want := make([]int, 0)
+			for i, box := range boxes {
+				if boxesOverlap(box, query) {
+					want = append(want, i)
+				}
+			}
+			if got := index.search(query); !reflect.DeepEqual(got, want) {
+				t.Fatalf("search(%v) in %d boxes = %v, want %v", query, n, got, want)
+			}
+		}
+	}
+
+	index := newSpatialIndex([]shp.Box{{MaxX: 1, MaxY: 1}, {MinX: 2, MinY: 2, MaxX: 3, MaxY: 3}})
+	if index.valid(3) {
+		t.Error("an index of 2 boxes is valid for 3 units")
+	}
+	index.Items[0] = index.Items[1]
+	if index.valid(2) {
+		t.Error("an index with a repeated item is valid")
+	}
+}
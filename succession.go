@@ -0,0 +1,183 @@
+package gomuni
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+//Types of the ISTAT administrative changes, as found in the Event of a TownSuccession
+const (
+	EventExtinction = "ES" // the town was merged into another or in a new one
+	EventNameChange = "CD" // the town was renamed
+)
+
+//TownSuccession is a town that took over a retired ISTAT code.
+//The Event is the ISTAT type of the change and the Date is when it became effective.
+type TownSuccession struct {
+	ID    string    `json:"id"`
+	Name  string    `json:"name,omitempty"`
+	Event string    `json:"event"`
+	Date  time.Time `json:"date"`
+}
+
+//Successions maps the retired ISTAT town codes to their successors
+type Successions struct {
+	successors map[string][]TownSuccession
+}
+
+//NewSuccessions returns an empty registry
+func NewSuccessions() *Successions {
+	return &Successions{successors: make(map[string][]TownSuccession)}
+}
+
+//Add records that the town with the old ID was succeeded by another town
+func (s *Successions) Add(oldID string, successor TownSuccession) {
+	oldID, successor.ID = buildIstatID(oldID), buildIstatID(successor.ID)
+	if oldID == successor.ID {
+		return
+	}
+	for _, existing := range s.successors[oldID] {
+		if existing.ID == successor.ID {
+			return
+		}
+	}
+	s.successors[oldID] = append(s.successors[oldID], successor)
+}
+
+//ResolveTownID returns the current towns that succeeded the town with the provided ISTAT code,
+//following the chains of changes. Each TownSuccession has the event and date of its last change.
+//It returns nil if the code was never retired.
+func (s *Successions) ResolveTownID(oldID string) []TownSuccession {
+	oldID = buildIstatID(oldID)
+	if len(s.successors[oldID]) == 0 {
+		return nil
+	}
+
+	resolved := make([]TownSuccession, 0)
+	seen := map[string]bool{oldID: true}
+
+	var resolve func(id string)
+	resolve = func(id string) {
+		for _, successor := range s.successors[id] {
+			if seen[successor.ID] {
+				continue
+			}
+			seen[successor.ID] = true
+
+			if len(s.successors[successor.ID]) == 0 {
+				resolved = append(resolved, successor)
+			} else {
+				resolve(successor.ID)
+			}
+		}
+	}
+	resolve(oldID)
+
+	return resolved
+}
+
+//LoadSuccessions reads the successions from the CSV of the administrative changes published by ISTAT
+//(Variazioni amministrative e territoriali dei comuni), separated by semicolons or commas.
+//Only the changes retiring a code are read: the extinctions and the renames changing the code.
+//The other ones, like the transfers of territory, name an associated town that is still current.
+func LoadSuccessions(r io.Reader) (*Successions, error) {
+	reader, err := newCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSuccessions, err)
+	}
+	columns, err := successionHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSuccessions()
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSuccessions, err)
+		}
+
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		oldID, newID := value("oldID"), value("newID")
+		if oldID == "" || newID == "" {
+			continue
+		}
+
+		successor := TownSuccession{ID: newID, Name: value("newName"), Event: strings.ToUpper(value("event"))}
+		if successor.Event != EventExtinction && successor.Event != EventNameChange {
+			continue
+		}
+		if date := value("date"); date != "" {
+			if successor.Date, err = parseSuccessionDate(date); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSuccessions, line, err)
+			}
+		}
+		s.Add(oldID, successor)
+	}
+
+	return s, nil
+}
+
+//successionHeader returns the index of the columns of the ISTAT CSV, recognized by their header
+func successionHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, h := range header {
+		name := normalizeHeader(h)
+		successor := strings.Contains(name, "associat") || strings.Contains(name, "nuov")
+
+		var column string
+		switch {
+		case strings.Contains(name, "tipovariazione"):
+			column = "event"
+		case strings.HasPrefix(name, "codice") && strings.Contains(name, "comune"):
+			column = "oldID"
+			if successor {
+				column = "newID"
+			}
+		case strings.HasPrefix(name, "denominazione") && successor:
+			column = "newName"
+		case strings.HasPrefix(name, "datadecorrenza"):
+			column = "date"
+		default:
+			continue
+		}
+
+		// the first column wins, like the alphanumeric code before the numeric one
+		if _, found := columns[column]; !found {
+			columns[column] = i
+		}
+	}
+
+	for _, column := range []string{"oldID", "newID"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing the %s column in %s", ErrInvalidSuccessions, column, strings.Join(header, ", "))
+		}
+	}
+	return columns, nil
+}
+
+//parseSuccessionDate parses the dates of the ISTAT CSV, like 01/01/2018 or 2018-01-01
+func parseSuccessionDate(value string) (time.Time, error) {
+	for _, layout := range []string{"02/01/2006", "2006-01-02", "2/1/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
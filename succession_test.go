@@ -0,0 +1,69 @@
+package gomuni
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const variazioni = `Anno;Tipo variazione;Codice Regione;Codice Comune formato alfanumerico;Denominazione Comune;Codice del Comune associato alla variazione o nuovo codice Istat del Comune;Denominazione Comune associata alla variazione o nuova denominazione;Data decorrenza validità amministrativa
+2016;ES;01;001001;Alfa;001300;Alfabeta;01/01/2016
+2016;ES;01;001002;Beta;001300;Alfabeta;01/01/2016
+2019;ES;01;001300;Alfabeta;001310;Alfabeta Nuova;01/01/2019
+2018;ES;08;099001;Gamma;099030;Delta;01/01/2018
+2018;ES;08;099001;Gamma;099031;Epsilon;01/01/2018
+2018;AP;08;099002;Zeta;;;01/01/2018
+2018;AQES;08;099030;Delta;099014;Rimini;01/01/2018
+2018;CESS;08;099014;Rimini;099030;Delta;01/01/2018
+2017;CD;01;001272;Torino;001272;Turin;01/01/2017
+`
+
+func Test_ResolveTownID(t *testing.T) {
+	s, err := LoadSuccessions(strings.NewReader(variazioni))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"001001", []string{"001310"}},
+		{"1002", []string{"001310"}},
+		{"001300", []string{"001310"}},
+		{"099001", []string{"099030", "099031"}},
+		{"099002", nil},
+		{"001310", nil},
+		{"099030", nil},
+		{"099014", nil},
+		{"001272", nil},
+	}
+	for _, tt := range tests {
+		got := s.ResolveTownID(tt.id)
+		ids := make([]string, 0)
+		for _, succession := range got {
+			ids = append(ids, succession.ID)
+		}
+		if len(tt.want) == 0 && got != nil || len(tt.want) > 0 && strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ResolveTownID(%s) = %v, want %v", tt.id, ids, tt.want)
+		}
+	}
+
+	got := s.ResolveTownID("001001")[0]
+	want := TownSuccession{ID: "001310", Name: "Alfabeta Nuova", Event: EventExtinction, Date: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if got != want {
+		t.Errorf("ResolveTownID(001001) = %+v, want %+v", got, want)
+	}
+}
+
+func Test_LoadSuccessionsErrors(t *testing.T) {
+	if _, err := LoadSuccessions(strings.NewReader("Anno,Tipo variazione\n2016,ES\n")); !errors.Is(err, ErrInvalidSuccessions) {
+		t.Errorf("LoadSuccessions() without the codes error = %v, want ErrInvalidSuccessions", err)
+	}
+
+	latin1 := strings.Replace(variazioni, "validità", "validit\xe0", 1)
+	if _, err := LoadSuccessions(strings.NewReader(latin1)); err != nil {
+		t.Errorf("LoadSuccessions() of a Latin-1 CSV error = %v", err)
+	}
+}
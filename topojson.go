@@ -0,0 +1,587 @@
+package gomuni
+
+import (
+	"encoding/json"
+	"math"
+
+	shp "github.com/jonas-p/go-shp"
+)
+
+//The layers of a Topology
+const (
+	TopologyRegions = "regions"
+	TopologyCities  = "cities"
+	TopologyTowns   = "towns"
+)
+
+//TopologyOptions are the options of a Topology
+type TopologyOptions struct {
+	//Quantization is the number of distinct values of each coordinate, like 1e5, snapping the points to a grid
+	//of the bounding box of the Country. 0 keeps the coordinates as they are.
+	Quantization int
+	//Tolerance simplifies the arcs removing the points closer than the tolerance in metres, with the Method.
+	//Each arc is simplified once and its ends are kept, the adjacent units keep sharing their borders.
+	Tolerance float64
+	Method    SimplifyMethod
+}
+
+//Topology is a TopoJSON topology of the Country. The towns layer is made by arcs shared by the adjacent towns,
+//the cities and regions layers are made by the same arcs on the boundary of their towns.
+//The rings keep the orientation of the shapefiles, the outer rings are clockwise as expected by d3-geo.
+type Topology struct {
+	Type      string                    `json:"type"`
+	Transform *TopologyTransform        `json:"transform,omitempty"`
+	BBox      []float64                 `json:"bbox,omitempty"`
+	Objects   map[string]TopologyObject `json:"objects"`
+	Arcs      [][][2]float64            `json:"arcs"`
+
+	index *topologyIndex
+}
+
+//topologyIndex keeps the decoded arcs and the geometries of the units by layer,
+//with the spatial index of their bounding boxes and their positions by ID
+type topologyIndex struct {
+	arcs       []Ring
+	geometries map[string][]TopologyGeometry
+	spatial    map[string]spatialIndex
+	positions  map[string]map[string]int
+}
+
+//TopologyTransform converts the quantized positions of the arcs in longitude and latitude
+type TopologyTransform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+//TopologyObject is a layer of the Topology
+type TopologyObject struct {
+	Type       string             `json:"type"`
+	Geometries []TopologyGeometry `json:"geometries"`
+}
+
+//TopologyGeometry is the MultiPolygon of a unit: its polygons made by rings of arcs indexes,
+//a negative index ^i is the arc i reversed
+type TopologyGeometry struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Arcs       [][][]int              `json:"arcs"`
+}
+
+//MarshalTopoJSON returns the Country as a TopoJSON Topology
+func (c *Country) MarshalTopoJSON(opts TopologyOptions) ([]byte, error) {
+	return json.Marshal(c.Topology(opts))
+}
+
+//Topology returns the Country as a TopoJSON Topology with the regions, cities and towns layers
+func (c *Country) Topology(opts TopologyOptions) *Topology {
+	t := &topologyBuilder{
+		junctions: make(map[Point]*neighbors),
+		arcsByEnd: make(map[[2]Point][]int),
+	}
+	t.setTransform(c, opts.Quantization)
+
+	// the rings of the towns, in the grid coordinates
+	type townRings struct {
+		town  *Town
+		rings [][]Ring
+	}
+	towns := make([]townRings, 0)
+	for _, r := range c.Regions {
+		for _, city := range r.Cities {
+			for _, town := range city.Towns {
+				tr := townRings{town: town}
+				for _, p := range town.polygon {
+					rings := make([]Ring, 0, len(p))
+					for i, ring := range p {
+						if ring = t.ring(ring, i == 0); ring != nil {
+							rings = append(rings, ring)
+						} else if i == 0 {
+							break
+						}
+					}
+					if len(rings) > 0 {
+						tr.rings = append(tr.rings, rings)
+					}
+				}
+				towns = append(towns, tr)
+			}
+		}
+	}
+
+	for _, tr := range towns {
+		for _, p := range tr.rings {
+			for _, ring := range p {
+				t.join(ring)
+			}
+		}
+	}
+
+	townsLayer := TopologyObject{Type: "GeometryCollection", Geometries: make([]TopologyGeometry, 0, len(towns))}
+	townArcs := make(map[*Town][][][]int, len(towns))
+	for _, tr := range towns {
+		arcs := make([][][]int, 0, len(tr.rings))
+		for _, p := range tr.rings {
+			polygon := make([][]int, len(p))
+			for i, ring := range p {
+				polygon[i] = t.cut(ring)
+			}
+			arcs = append(arcs, polygon)
+		}
+		townArcs[tr.town] = arcs
+		townsLayer.Geometries = append(townsLayer.Geometries, TopologyGeometry{
+			Type: "MultiPolygon", ID: tr.town.ID, Properties: tr.town.properties(), Arcs: arcs,
+		})
+	}
+
+	// the arcs are simplified before merging them, their ends do not change
+	if opts.Tolerance > 0 {
+		for i, arc := range t.arcs {
+			t.arcs[i] = simplifyLine(arc, t.degrees(arc), opts.Tolerance, opts.Method)
+		}
+	}
+
+	citiesLayer := TopologyObject{Type: "GeometryCollection", Geometries: make([]TopologyGeometry, 0)}
+	regionsLayer := TopologyObject{Type: "GeometryCollection", Geometries: make([]TopologyGeometry, 0, len(c.Regions))}
+	for _, r := range c.Regions {
+		regionTowns := make([][][][]int, 0)
+		for _, city := range r.Cities {
+			cityTowns := make([][][][]int, 0, len(city.Towns))
+			for _, town := range city.Towns {
+				cityTowns = append(cityTowns, townArcs[town])
+			}
+			regionTowns = append(regionTowns, cityTowns...)
+			citiesLayer.Geometries = append(citiesLayer.Geometries, TopologyGeometry{
+				Type: "MultiPolygon", ID: city.ID, Properties: city.properties(), Arcs: t.merge(cityTowns),
+			})
+		}
+		regionsLayer.Geometries = append(regionsLayer.Geometries, TopologyGeometry{
+			Type: "MultiPolygon", ID: r.ID, Properties: r.properties(), Arcs: t.merge(regionTowns),
+		})
+	}
+
+	objects := map[string]TopologyObject{
+		TopologyRegions: regionsLayer,
+		TopologyCities:  citiesLayer,
+		TopologyTowns:   townsLayer,
+	}
+	index := &topologyIndex{
+		arcs:       make([]Ring, len(t.arcs)),
+		geometries: make(map[string][]TopologyGeometry, len(objects)),
+		spatial:    make(map[string]spatialIndex, len(objects)),
+		positions:  make(map[string]map[string]int, len(objects)),
+	}
+	arcBoxes := make([][4]float64, len(t.arcs))
+	for i, arc := range t.arcs {
+		index.arcs[i] = t.degrees(arc)
+		arcBoxes[i] = [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, p := range index.arcs[i] {
+			arcBoxes[i] = [4]float64{
+				math.Min(arcBoxes[i][0], p.Lng), math.Min(arcBoxes[i][1], p.Lat),
+				math.Max(arcBoxes[i][2], p.Lng), math.Max(arcBoxes[i][3], p.Lat),
+			}
+		}
+	}
+	for layer, obj := range objects {
+		index.geometries[layer] = obj.Geometries
+		boxes := make([]shp.Box, len(obj.Geometries))
+		index.positions[layer] = make(map[string]int, len(obj.Geometries))
+		for i, g := range obj.Geometries {
+			box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+			for _, polygon := range g.Arcs {
+				// the outer ring contains the holes
+				for _, index := range polygon[0] {
+					if index < 0 {
+						index = ^index
+					}
+					b := arcBoxes[index]
+					box = [4]float64{math.Min(box[0], b[0]), math.Min(box[1], b[1]), math.Max(box[2], b[2]), math.Max(box[3], b[3])}
+				}
+			}
+			// with the latitudes on the X axis, as the bounding boxes of the units
+			boxes[i] = shp.Box{MinX: box[1], MinY: box[0], MaxX: box[3], MaxY: box[2]}
+			index.positions[layer][g.ID] = i
+		}
+		index.spatial[layer] = newSpatialIndex(boxes)
+	}
+
+	return &Topology{
+		Type:      "Topology",
+		Transform: t.transform,
+		BBox:      t.bbox,
+		Objects:   objects,
+		Arcs:      t.encodeArcs(),
+		index:     index,
+	}
+}
+
+//MultiPolygon returns the boundary of the unit with the ID in the layer, made by the arcs of the Topology.
+//It is nil if the unit is not found or if the Topology was not returned by Country.Topology.
+func (t *Topology) MultiPolygon(layer, id string) MultiPolygon {
+	if t.index == nil {
+		return nil
+	}
+	i, ok := t.index.positions[layer][id]
+	if !ok {
+		return nil
+	}
+	return arcsMultiPolygon(t.index.geometries[layer][i].Arcs, t.index.arcs)
+}
+
+//arcsMultiPolygon returns the MultiPolygon made by the rings of arcs indexes
+func arcsMultiPolygon(rings [][][]int, arcs []Ring) MultiPolygon {
+	m := make(MultiPolygon, 0, len(rings))
+	for _, polygon := range rings {
+		p := make(Polygon, 0, len(polygon))
+		for _, ringArcs := range polygon {
+			p = append(p, arcsRing(ringArcs, arcs))
+		}
+		m = append(m, p)
+	}
+	return m
+}
+
+//arcsRing returns the points of a ring of arcs indexes, a negative index ^i is the arc i reversed
+func arcsRing(ring []int, arcs []Ring) Ring {
+	points := make(Ring, 0)
+	for _, index := range ring {
+		if index < 0 {
+			arc := arcs[^index]
+			for i := len(arc) - 1; i >= 0; i-- {
+				if len(points) == 0 || i < len(arc)-1 {
+					points = append(points, arc[i])
+				}
+			}
+			continue
+		}
+		for i, p := range arcs[index] {
+			if len(points) == 0 || i > 0 {
+				points = append(points, p)
+			}
+		}
+	}
+	return points
+}
+
+//neighbors are the points before and after a point of a ring, the point is a junction
+//if it has other neighbors in another ring
+type neighbors struct {
+	hash     uint64
+	junction bool
+}
+
+//topologyBuilder splits the rings of the towns in arcs, cutting them at the junctions,
+//where the towns sharing a boundary change
+type topologyBuilder struct {
+	transform *TopologyTransform
+	bbox      []float64
+
+	junctions map[Point]*neighbors
+	arcs      []Ring
+	arcsByEnd map[[2]Point][]int
+}
+
+//setTransform computes the bounding box of the Country and the transform of the quantization
+func (t *topologyBuilder) setTransform(c *Country, quantization int) {
+	first := true
+	var bbox [4]float64
+	for _, r := range c.Regions {
+		if len(r.polygon) == 0 {
+			continue
+		}
+		b := r.polygon.BBox()
+		if first {
+			bbox = [4]float64{b.MinY, b.MinX, b.MaxY, b.MaxX}
+			first = false
+			continue
+		}
+		bbox = [4]float64{math.Min(bbox[0], b.MinY), math.Min(bbox[1], b.MinX), math.Max(bbox[2], b.MaxY), math.Max(bbox[3], b.MaxX)}
+	}
+	if first {
+		return
+	}
+	t.bbox = bbox[:]
+
+	if quantization > 1 {
+		scale := func(min, max float64) float64 {
+			if max > min {
+				return (max - min) / float64(quantization-1)
+			}
+			return 1
+		}
+		t.transform = &TopologyTransform{
+			Scale:     [2]float64{scale(bbox[0], bbox[2]), scale(bbox[1], bbox[3])},
+			Translate: [2]float64{bbox[0], bbox[1]},
+		}
+	}
+}
+
+//ring returns the Ring in the grid coordinates without repeated points, closed and with the orientation
+//of the shapefiles: clockwise the outer rings and counterclockwise the holes. It is nil if the ring collapses.
+func (t *topologyBuilder) ring(ring Ring, outer bool) Ring {
+	grid := make(Ring, 0, len(ring)+1)
+	for _, p := range ring {
+		if t.transform != nil {
+			p = Point{
+				Lat: math.Round((p.Lat - t.transform.Translate[1]) / t.transform.Scale[1]),
+				Lng: math.Round((p.Lng - t.transform.Translate[0]) / t.transform.Scale[0]),
+			}
+		}
+		if len(grid) == 0 || grid[len(grid)-1] != p {
+			grid = append(grid, p)
+		}
+	}
+	if len(grid) > 0 && grid[0] != grid[len(grid)-1] {
+		grid = append(grid, grid[0])
+	}
+	if len(grid) < 4 {
+		return nil
+	}
+
+	if clockwise := grid.signedArea() < 0; clockwise != outer {
+		for i, j := 0, len(grid)-1; i < j; i, j = i+1, j-1 {
+			grid[i], grid[j] = grid[j], grid[i]
+		}
+	}
+	return grid
+}
+
+//degrees returns the points of the arc in longitude and latitude, from the grid coordinates
+func (t *topologyBuilder) degrees(arc Ring) Ring {
+	if t.transform == nil {
+		return arc
+	}
+	points := make(Ring, len(arc))
+	for i, p := range arc {
+		points[i] = Point{
+			Lat: p.Lat*t.transform.Scale[1] + t.transform.Translate[1],
+			Lng: p.Lng*t.transform.Scale[0] + t.transform.Translate[0],
+		}
+	}
+	return points
+}
+
+//join records the neighbors of the points of the closed ring, finding the junctions
+func (t *topologyBuilder) join(ring Ring) {
+	n := len(ring) - 1
+	for i := 0; i < n; i++ {
+		prev, next := ring[(i+n-1)%n], ring[i+1]
+		// the sum does not depend on the direction of the ring
+		hash := pointHash(prev) + pointHash(next)
+
+		if nb, ok := t.junctions[ring[i]]; !ok {
+			t.junctions[ring[i]] = &neighbors{hash: hash}
+		} else if nb.hash != hash {
+			nb.junction = true
+		}
+	}
+}
+
+//pointHash mixes the bits of the coordinates of the point, with the finalizer of splitmix64
+func pointHash(p Point) uint64 {
+	h := math.Float64bits(p.Lat)*31 + math.Float64bits(p.Lng)
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+//cut splits the closed ring at its junctions and returns the indexes of its arcs
+func (t *topologyBuilder) cut(ring Ring) []int {
+	n := len(ring) - 1
+
+	start := -1
+	for i := 0; i < n; i++ {
+		if t.junctions[ring[i]].junction {
+			start = i
+			break
+		}
+	}
+
+	// without junctions the ring is a single arc, starting from its smallest point to be found in any direction
+	if start < 0 {
+		start = 0
+		for i := 1; i < n; i++ {
+			if lessPoint(ring[i], ring[start]) {
+				start = i
+			}
+		}
+		arc := make(Ring, 0, n+1)
+		arc = append(arc, ring[start:n]...)
+		arc = append(arc, ring[:start+1]...)
+		return []int{t.arcIndex(arc)}
+	}
+
+	arcs := make([]int, 0)
+	arc := Ring{ring[start]}
+	for k := 1; k <= n; k++ {
+		p := ring[(start+k)%n]
+		arc = append(arc, p)
+		if t.junctions[p].junction {
+			arcs = append(arcs, t.arcIndex(arc))
+			arc = Ring{p}
+		}
+	}
+	return arcs
+}
+
+//lessPoint sorts the points by longitude and latitude
+func lessPoint(a, b Point) bool {
+	return a.Lng < b.Lng || (a.Lng == b.Lng && a.Lat < b.Lat)
+}
+
+//arcIndex returns the index of the arc, adding it if new. The index is ^i if the arc i is the reversed one.
+func (t *topologyBuilder) arcIndex(arc Ring) int {
+	first, last := arc[0], arc[len(arc)-1]
+
+	for _, i := range t.arcsByEnd[[2]Point{first, last}] {
+		if sameArc(t.arcs[i], arc, false) {
+			return i
+		}
+	}
+	for _, i := range t.arcsByEnd[[2]Point{last, first}] {
+		if sameArc(t.arcs[i], arc, true) {
+			return ^i
+		}
+	}
+
+	i := len(t.arcs)
+	t.arcs = append(t.arcs, arc)
+	t.arcsByEnd[[2]Point{first, last}] = append(t.arcsByEnd[[2]Point{first, last}], i)
+	return i
+}
+
+//sameArc checks if the arcs have the same points, in the opposite order if reversed
+func sameArc(a, b Ring, reversed bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		j := i
+		if reversed {
+			j = len(b) - 1 - i
+		}
+		if a[i] != b[j] {
+			return false
+		}
+	}
+	return true
+}
+
+//arcEnds returns the first and the last point of the arc with the index, reversed if negative
+func (t *topologyBuilder) arcEnds(index int) (Point, Point) {
+	if index < 0 {
+		arc := t.arcs[^index]
+		return arc[len(arc)-1], arc[0]
+	}
+	arc := t.arcs[index]
+	return arc[0], arc[len(arc)-1]
+}
+
+//merge returns the union of the MultiPolygons of the towns: the arcs used by a single town are its boundary,
+//the ones shared by two towns are inside and they are dropped. The boundary arcs are chained in rings,
+//grouped in polygons like the rings of a shapefile.
+func (t *topologyBuilder) merge(towns [][][][]int) [][][]int {
+	count := make(map[int]int)
+	for _, town := range towns {
+		for _, p := range town {
+			for _, ring := range p {
+				for _, index := range ring {
+					if index < 0 {
+						index = ^index
+					}
+					count[index]++
+				}
+			}
+		}
+	}
+
+	boundary := make([]int, 0)
+	byStart := make(map[Point][]int)
+	for _, town := range towns {
+		for _, p := range town {
+			for _, ring := range p {
+				for _, index := range ring {
+					i := index
+					if i < 0 {
+						i = ^i
+					}
+					if count[i] == 1 {
+						start, _ := t.arcEnds(index)
+						byStart[start] = append(byStart[start], index)
+						boundary = append(boundary, index)
+					}
+				}
+			}
+		}
+	}
+
+	used := make(map[int]bool, len(boundary))
+	rings := make([][]int, 0)
+	points := make([]Ring, 0)
+	for _, index := range boundary {
+		if used[index] {
+			continue
+		}
+		used[index] = true
+		ring := []int{index}
+		start, end := t.arcEnds(index)
+		for end != start {
+			next, found := 0, false
+			for _, candidate := range byStart[end] {
+				if !used[candidate] {
+					next, found = candidate, true
+					break
+				}
+			}
+			if !found {
+				break
+			}
+			used[next] = true
+			ring = append(ring, next)
+			_, end = t.arcEnds(next)
+		}
+		if end != start {
+			// an open chain is left by boundaries not matching exactly, it is not a ring
+			continue
+		}
+		rings = append(rings, ring)
+		points = append(points, t.ringPoints(ring))
+	}
+
+	groups := groupRings(points)
+	polygons := make([][][]int, len(groups))
+	for i, group := range groups {
+		polygons[i] = make([][]int, len(group))
+		for j, r := range group {
+			polygons[i][j] = rings[r]
+		}
+	}
+	return polygons
+}
+
+//ringPoints returns the points of a ring of arcs
+func (t *topologyBuilder) ringPoints(ring []int) Ring {
+	return arcsRing(ring, t.arcs)
+}
+
+//encodeArcs returns the arcs as TopoJSON positions, delta encoded if quantized
+func (t *topologyBuilder) encodeArcs() [][][2]float64 {
+	encoded := make([][][2]float64, len(t.arcs))
+	for i, arc := range t.arcs {
+		positions := make([][2]float64, len(arc))
+		var prev Point
+		for j, p := range arc {
+			if t.transform != nil {
+				positions[j] = [2]float64{p.Lng - prev.Lng, p.Lat - prev.Lat}
+				prev = p
+				continue
+			}
+			positions[j] = [2]float64{p.Lng, p.Lat}
+		}
+		encoded[i] = positions
+	}
+	return encoded
+}
@@ -0,0 +1,118 @@
+package gomuni
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+//decodeTopology returns the MultiPolygon of a geometry of the Topology
+func decodeTopology(topology *Topology, geometry TopologyGeometry) MultiPolygon {
+	arcs := make([]Ring, len(topology.Arcs))
+	for i, arc := range topology.Arcs {
+		var x, y float64
+		for _, position := range arc {
+			p := Point{Lat: position[1], Lng: position[0]}
+			if tr := topology.Transform; tr != nil {
+				x, y = x+position[0], y+position[1]
+				p = Point{Lat: y*tr.Scale[1] + tr.Translate[1], Lng: x*tr.Scale[0] + tr.Translate[0]}
+			}
+			arcs[i] = append(arcs[i], p)
+		}
+	}
+
+	m := make(MultiPolygon, 0)
+	for _, polygon := range geometry.Arcs {
+		p := make(Polygon, 0)
+		for _, ringArcs := range polygon {
+			ring := make(Ring, 0)
+			for _, index := range ringArcs {
+				var arc Ring
+				if index >= 0 {
+					arc = arcs[index]
+				} else {
+					arc = append(Ring{}, arcs[^index]...)
+					for i, j := 0, len(arc)-1; i < j; i, j = i+1, j-1 {
+						arc[i], arc[j] = arc[j], arc[i]
+					}
+				}
+				if len(ring) > 0 {
+					arc = arc[1:]
+				}
+				ring = append(ring, arc...)
+			}
+			p = append(p, ring)
+		}
+		m = append(m, p)
+	}
+	return m
+}
+
+func Test_Topology(t *testing.T) {
+	country := newTestCountry()
+
+	topology := country.Topology(TopologyOptions{})
+	if topology.Type != "Topology" || topology.Transform != nil {
+		t.Fatalf("unexpected topology %+v", topology)
+	}
+	// the border of Torino and Moncalieri is shared, the hole of Rimini is an arc too
+	if len(topology.Arcs) != 5 {
+		t.Errorf("got %d arcs, want 5: %v", len(topology.Arcs), topology.Arcs)
+	}
+
+	towns := topology.Objects[TopologyTowns].Geometries
+	cities := topology.Objects[TopologyCities].Geometries
+	regions := topology.Objects[TopologyRegions].Geometries
+	if len(towns) != 3 || len(cities) != 2 || len(regions) != 2 {
+		t.Fatalf("got %d towns, %d cities and %d regions", len(towns), len(cities), len(regions))
+	}
+	if towns[1].ID != "001156" || towns[1].Properties["name"] != "Moncalieri" {
+		t.Errorf("unexpected town %+v", towns[1])
+	}
+
+	// the city of Torino is made by the outer arcs of its towns, without the shared one
+	if torino := cities[0].Arcs; len(torino) != 1 || len(torino[0]) != 1 || len(torino[0][0]) != 2 {
+		t.Errorf("unexpected arcs of Torino %v", torino)
+	}
+	if rimini := regions[1].Arcs; len(rimini) != 1 || len(rimini[0]) != 2 {
+		t.Errorf("unexpected arcs of Emilia-Romagna %v", rimini)
+	}
+
+	points := []Point{{45, 7.5}, {45, 8.5}, {45, 10.5}, {45, 11}, {45, 9.5}, {43, 7.5}}
+	for _, quantization := range []int{0, 1e4} {
+		topology := country.Topology(TopologyOptions{Quantization: quantization})
+		for _, g := range topology.Objects[TopologyRegions].Geometries {
+			region := country.GetRegionByID(g.ID)
+			m := decodeTopology(topology, g)
+			for _, p := range points {
+				if m.Contains(p) != region.Contains(p) {
+					t.Errorf("quantization %d: region %s Contains(%v) = %v", quantization, g.ID, p, m.Contains(p))
+				}
+			}
+		}
+		for _, g := range topology.Objects[TopologyTowns].Geometries {
+			town := country.GetTownByID(g.ID)
+			m := decodeTopology(topology, g)
+			for _, p := range points {
+				if m.Contains(p) != town.Contains(p) {
+					t.Errorf("quantization %d: town %s Contains(%v) = %v", quantization, g.ID, p, m.Contains(p))
+				}
+			}
+		}
+	}
+
+	b, err := country.MarshalTopoJSON(TopologyOptions{Quantization: 1e4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Topology
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, arc := range decoded.Arcs {
+		for _, position := range arc {
+			if position[0] != float64(int(position[0])) || position[1] != float64(int(position[1])) {
+				t.Fatalf("the quantized position %v is not an integer", position)
+			}
+		}
+	}
+}
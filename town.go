@@ -1,30 +1,39 @@
 package gomuni
 
 import (
-	"github.com/dhconnelly/rtreego"
 	shp "github.com/jonas-p/go-shp"
-	geo "github.com/kellydunn/golang-geo"
 )
 
 //Town represent an italian Town (comune)
 type Town struct {
-	ID       string `json:"id,omitempty"`
-	RegionID string `json:"region_id,omitempty"`
-	CityID   string `json:"city_id,omitempty"`
-	Name     string `json:"name,omitempty"`
+	ID         string            `json:"id,omitempty"`
+	RegionID   string            `json:"region_id,omitempty"`
+	CityID     string            `json:"city_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Names      Names             `json:"names,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
 
 	BBox    shp.Box `json:"bbox,omitempty"`
-	polygon *geo.Polygon
-}
-
-//Bounds is used to implement the rtreego Spatial interface
-func (t *Town) Bounds() *rtreego.Rect {
-	p1 := rtreego.Point{t.BBox.MinX, t.BBox.MinY}
-	r1, _ := rtreego.NewRect(p1, []float64{t.BBox.MaxX - t.BBox.MinX, t.BBox.MaxY - t.BBox.MinY})
-	return r1
+	polygon MultiPolygon
 }
 
 //Contains check if the current Town contains the passed in Point.
 func (t *Town) Contains(point Point) bool {
-	return t.polygon.Contains(geo.NewPoint(point.Lat, point.Lng))
+	return t.polygon.Contains(point)
+}
+
+//Centroid returns the centroid of the polygon of the Town
+func (t *Town) Centroid() Point {
+	return t.polygon.Centroid()
+}
+
+//Geometry returns the boundary of the Town, with its holes and islands
+func (t *Town) Geometry() Geometry {
+	return t.polygon.Geometry()
+}
+
+//SimplifiedGeometry returns the boundary of the Town simplified with the tolerance in metres.
+//Its borders can diverge from the ones of its neighbors, a Topology simplifies them once for all the units.
+func (t *Town) SimplifiedGeometry(tolerance float64, method SimplifyMethod) Geometry {
+	return t.polygon.Simplify(tolerance, method).Geometry()
 }